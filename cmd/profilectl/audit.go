@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/bootstrap"
+	"github.com/Brrocat/user-profile-service/internal/config"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"log/slog"
+)
+
+func auditPurge(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("audit purge", flag.ContinueOnError)
+	retention := fs.Duration("retention", cfg.AuditRetention, "delete access records and break-glass grants older than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *retention < config.MinAuditRetention {
+		return fmt.Errorf("retention must be at least %s, got %s", config.MinAuditRetention, *retention)
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	purger := service.NewAccessLogPurger(profileRepo, *retention, logger)
+	purged, err := purger.Purge(ctx)
+	fmt.Printf("purged=%d\n", purged)
+	if err != nil {
+		return err
+	}
+
+	grants, err := purger.PurgeGrants(ctx)
+	fmt.Printf("purged_grants=%d\n", grants)
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/bootstrap"
+	"github.com/Brrocat/user-profile-service/internal/config"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"log/slog"
+	"strings"
+)
+
+func cacheVerify(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("cache verify", flag.ContinueOnError)
+	repair := fs.String("repair", "none", "how to fix drifted entries: none, rewrite or evict")
+	batchSize := fs.Int("batch", 500, "number of keys scanned per Redis round trip")
+	users := fs.String("users", "", "comma-separated user IDs to check instead of scanning the whole cache")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repairMode, err := service.ParseRepairMode(*repair)
+	if err != nil {
+		return err
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	cacheRepo, err := bootstrap.NewCacheRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer cacheRepo.Close()
+
+	verifier := service.NewCacheVerifier(profileRepo, cacheRepo, repairMode, logger)
+
+	var report *service.VerifyReport
+	if *users != "" {
+		report, err = verifier.VerifyUsers(ctx, strings.Split(*users, ","))
+	} else {
+		report, err = verifier.VerifyAll(ctx, *batchSize)
+	}
+	if report != nil {
+		printVerifyReport(report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(report.Mismatches) > report.Repaired {
+		return errFindings
+	}
+	return nil
+}
+
+func printVerifyReport(report *service.VerifyReport) {
+	for _, m := range report.Mismatches {
+		if len(m.Fields) > 0 {
+			fmt.Printf("%s\t%s\t%s\n", m.UserID, m.Kind, strings.Join(m.Fields, ","))
+		} else {
+			fmt.Printf("%s\t%s\n", m.UserID, m.Kind)
+		}
+	}
+
+	fmt.Printf("checked=%d mismatches=%d repaired=%d drift=%.4f\n",
+		report.Checked, len(report.Mismatches), report.Repaired, report.DriftRatio())
+}
+
+func cacheWarm(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("cache warm", flag.ContinueOnError)
+	limit := fs.Int("limit", cfg.CacheWarmupLimit, "number of most recently updated profiles to preload")
+	batchSize := fs.Int("batch", cfg.CacheWarmupBatchSize, "profiles read and cached per round trip")
+	rowsPerSecond := fs.Int("rate", cfg.CacheWarmupRate, "maximum database rows read per second, 0 for unlimited")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	cacheRepo, err := bootstrap.NewCacheRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer cacheRepo.Close()
+
+	warmer, err := service.NewCacheWarmer(profileRepo, cacheRepo, service.WarmupOptions{
+		Limit:         *limit,
+		BatchSize:     *batchSize,
+		RowsPerSecond: *rowsPerSecond,
+	}, logger)
+	if err != nil {
+		return err
+	}
+
+	loaded, err := warmer.Run(ctx)
+	fmt.Printf("loaded=%d\n", loaded)
+	return err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/bootstrap"
+	"github.com/Brrocat/user-profile-service/internal/config"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"log/slog"
+	"time"
+)
+
+func encryptionMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("encryption migrate", flag.ContinueOnError)
+	batchSize := fs.Int("batch", 500, "rows encrypted per transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.EncryptionKeyringFile == "" {
+		return errors.New("ENCRYPTION_KEYRING_FILE is not set")
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	encrypted, err := profileRepo.EncryptPlaintextRows(ctx, *batchSize, func(done int) {
+		logger.Info("Encrypted plaintext profiles", "rows", done)
+	})
+	fmt.Printf("encrypted=%d\n", encrypted)
+	return err
+}
+
+func encryptionReindex(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("encryption reindex", flag.ContinueOnError)
+	batchSize := fs.Int("batch", 500, "rows indexed per transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.EncryptionKeyringFile == "" {
+		return errors.New("ENCRYPTION_KEYRING_FILE is not set")
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	indexed, err := profileRepo.BackfillBlindIndexes(ctx, *batchSize, func(done int) {
+		logger.Info("Computed blind indexes", "rows", done)
+	})
+	fmt.Printf("indexed=%d\n", indexed)
+	return err
+}
+
+func keysRotate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	batchSize := fs.Int("batch", 500, "profiles rewrapped per transaction")
+	rowsPerSecond := fs.Int("rate", 1000, "maximum profiles rewritten per second, 0 for unlimited")
+	restart := fs.Bool("restart", false, "discard the checkpoint of a previous run to the same key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.EncryptionKeyringFile == "" {
+		return errors.New("ENCRYPTION_KEYRING_FILE is not set")
+	}
+
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	rotator, err := service.NewKeyRotator(profileRepo, service.KeyRotationOptions{
+		BatchSize:     *batchSize,
+		RowsPerSecond: *rowsPerSecond,
+		Restart:       *restart,
+	}, logger)
+	if err != nil {
+		return err
+	}
+
+	rotation, err := rotator.Run(ctx)
+	if rotation != nil {
+		printKeyRotation(rotation)
+	}
+	return err
+}
+
+func keysStatus(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer profileRepo.Close()
+
+	rotations, err := profileRepo.ListKeyRotations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rotation := range rotations {
+		printKeyRotation(rotation)
+	}
+	return nil
+}
+
+func printKeyRotation(rotation *postgres.KeyRotation) {
+	status := "in_progress"
+	if rotation.CompletedAt != nil {
+		status = "completed"
+	}
+	fmt.Printf("key=%s status=%s scanned=%d rewrapped=%d skipped=%d total=%d started=%s updated=%s\n",
+		rotation.KeyID, status, rotation.Scanned, rotation.Rewrapped, len(rotation.Skipped), rotation.Total,
+		rotation.StartedAt.Format(time.RFC3339), rotation.UpdatedAt.Format(time.RFC3339))
+}
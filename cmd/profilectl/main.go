@@ -0,0 +1,80 @@
+// Command profilectl runs maintenance tasks against the user profile
+// service's database and cache.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/config"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+type command struct {
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error
+}
+
+var commands = map[string]command{
+	"audit purge":        {"delete access records and break-glass grants older than the retention period", auditPurge},
+	"cache verify":       {"compare cached profiles with the database and optionally repair them", cacheVerify},
+	"cache warm":         {"preload the most recently updated profiles into the cache", cacheWarm},
+	"encryption migrate": {"encrypt personal data stored before encryption was enabled", encryptionMigrate},
+	"encryption reindex": {"compute blind indexes of rows written before an index key was configured", encryptionReindex},
+	"keys rotate":        {"rewrap encrypted personal data with the primary key of the keyring", keysRotate},
+	"keys status":        {"show the progress of key rotations", keysStatus},
+}
+
+// errFindings makes the command exit with status 1 without printing an error.
+var errFindings = errors.New("findings reported")
+
+func main() {
+	if len(os.Args) < 3 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]+" "+os.Args[2]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		os.Exit(1)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, cfg, logger, os.Args[3:]); err != nil {
+		if !errors.Is(err, errFindings) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		stop()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: profilectl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].summary)
+	}
+}
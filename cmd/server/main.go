@@ -1,18 +1,43 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"github.com/Brrocat/car-sharing-protos/proto/userprofile"
+	"github.com/Brrocat/user-profile-service/internal/audit"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/bootstrap"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/certs"
 	"github.com/Brrocat/user-profile-service/internal/config"
 	"github.com/Brrocat/user-profile-service/internal/handler"
-	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
-	"github.com/Brrocat/user-profile-service/internal/repository/redis"
+	"github.com/Brrocat/user-profile-service/internal/health"
+	"github.com/Brrocat/user-profile-service/internal/interceptor"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
 	"github.com/Brrocat/user-profile-service/internal/service"
+	"github.com/Brrocat/user-profile-service/internal/tracing"
 	"github.com/Brrocat/user-profile-service/pkg/validation"
+	"github.com/Brrocat/user-profile-service/proto/accesslog"
+	"github.com/Brrocat/user-profile-service/proto/profilelookup"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"log"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -23,22 +48,61 @@ func main() {
 	}
 
 	// Setup logger
-	logger := setupLogger(cfg.Env)
+	logger, logLevel, err := bootstrap.SetupLogger(cfg.Env, cfg.LogLevel)
+	if err != nil {
+		log.Fatal("Failed to setup logger:", err)
+	}
+
+	if err := run(cfg, logger, logLevel); err != nil {
+		logger.Error("User profile service stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config.Config, logger *slog.Logger, logLevel *slog.LevelVar) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Setup tracing before the repositories so they pick up the tracer provider
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		ServiceName: "user-profile-service",
+		Exporter:    cfg.TracingExporter,
+		OutputFile:  cfg.TracingOutputFile,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	// Flush spans last, after everything else has shut down
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Warn("Failed to flush traces", "error", err)
+		}
+	}()
 
 	// Initialize repositories
-	profileRepo, err := postgres.NewProfileRepository(cfg.DatabaseURL)
+	profileRepo, err := bootstrap.NewProfileRepository(cfg)
 	if err != nil {
-		logger.Error("Failed to connect to database", "error", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer profileRepo.Close()
 
-	cacheRepo, err := redis.NewCacheRepository(cfg.RedisURL)
+	cacheRepo, err := bootstrap.NewCacheRepository(cfg)
 	if err != nil {
-		logger.Error("Failed to connect to Redis", "error", err)
-		os.Exit(1)
+		profileRepo.Close()
+		return fmt.Errorf("failed to initialize Redis cache: %w", err)
 	}
-	defer cacheRepo.Close()
+
+	// Close the database pool first, then Redis
+	defer func() {
+		profileRepo.Close()
+		cacheRepo.Close()
+		logger.Info("Connections closed")
+	}()
+
+	// Background workers are stopped after the servers have drained
+	workers := newWorkerGroup()
+	defer workers.Stop()
 
 	// Initialize utilities
 	validator := validation.NewValidator()
@@ -46,38 +110,328 @@ func main() {
 	// Initialize service
 	profileService := service.NewProfileService(profileRepo, cacheRepo, validator, logger)
 
-	// Initialize gRPC handler
-	profileHandler := handler.NewProfileHandler(profileService, logger)
+	// Start background cache verification
+	if cfg.CacheVerifyInterval > 0 {
+		repairMode, err := service.ParseRepairMode(cfg.CacheVerifyRepair)
+		if err != nil {
+			return fmt.Errorf("invalid cache verification configuration: %w", err)
+		}
+		verifier := service.NewCacheVerifier(profileRepo, cacheRepo, repairMode, logger)
+		workers.Go(func(ctx context.Context) {
+			verifier.RunSampler(ctx, cfg.CacheVerifyInterval, cfg.CacheVerifySampleSize)
+		})
+	}
+
+	// Preload the cache in the background
+	if cfg.CacheWarmupEnabled {
+		warmer, err := service.NewCacheWarmer(profileRepo, cacheRepo, service.WarmupOptions{
+			Limit:         cfg.CacheWarmupLimit,
+			BatchSize:     cfg.CacheWarmupBatchSize,
+			RowsPerSecond: cfg.CacheWarmupRate,
+		}, logger)
+		if err != nil {
+			return fmt.Errorf("invalid cache warm-up configuration: %w", err)
+		}
+		workers.Go(func(ctx context.Context) {
+			if _, err := warmer.Run(ctx); err != nil {
+				logger.Warn("Cache warm-up failed", "error", err)
+			}
+		})
+	}
+
+	// Authenticate callers on every listener and authorize them in front of
+	// the service
+	var tokenVerifier *auth.Verifier
+	var profiles service.Profiles = profileService
+	var accessLog service.AccessLog = service.NewAccessLogService(profileRepo, logger)
+	var breakGlass *service.BreakGlassService
+	var breakGlassAPI service.BreakGlass
+	if cfg.AuthEnabled {
+		tokenVerifier, err = auth.NewVerifier(auth.VerifierConfig{
+			JWKSFile:       cfg.AuthJWKSFile,
+			PublicKeyFiles: cfg.AuthPublicKeyFiles,
+			Issuer:         cfg.AuthIssuer,
+			Audience:       cfg.AuthAudience,
+			ServiceScope:   cfg.AuthServiceScope,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid authentication configuration: %w", err)
+		}
+
+		policy := authz.DefaultPolicy()
+		if cfg.RBACPolicyFile != "" {
+			if policy, err = authz.LoadPolicy(cfg.RBACPolicyFile); err != nil {
+				return err
+			}
+		}
+		// Reads under break-glass grants are announced once recorded, so
+		// grants need the access log
+		if cfg.AuditEnabled {
+			notifier := breakglass.NewNotifier(bootstrap.SetupAuditLogger(cfg.Env), cfg.BreakGlassWebhookURL, logger)
+			breakGlass, err = service.NewBreakGlassService(profileRepo, notifier, service.BreakGlassOptions{
+				DefaultTTL: cfg.BreakGlassDefaultTTL,
+				MaxTTL:     cfg.BreakGlassMaxTTL,
+			}, logger)
+			if err != nil {
+				return fmt.Errorf("invalid break-glass configuration: %w", err)
+			}
+			workers.Go(notifier.Run)
+			breakGlassAPI = service.NewAuthorizedBreakGlassService(breakGlass, policy, logger)
+		} else {
+			logger.Warn("Break-glass access is disabled because the access audit log is disabled")
+		}
+		profiles = service.NewAuthorizedProfileService(profiles, policy, breakGlass, logger)
+		accessLog = service.NewAuthorizedAccessLogService(accessLog, policy, logger)
+	} else {
+		logger.Warn("Authentication is disabled, every caller can access every profile")
+	}
+
+	// Record reads of personal data, outside authorization so that only the
+	// fields actually returned are recorded
+	if cfg.AuditEnabled {
+		writer, err := audit.NewWriter(profileRepo, audit.WriterOptions{
+			BufferSize:    cfg.AuditBufferSize,
+			BatchSize:     cfg.AuditBatchSize,
+			FlushInterval: cfg.AuditFlushInterval,
+		}, logger)
+		if err != nil {
+			return fmt.Errorf("invalid access log configuration: %w", err)
+		}
+		workers.Go(writer.Run)
+		profiles = service.NewAuditedProfileService(profiles, writer, breakGlass, logger)
+
+		if cfg.AuditRetention > 0 {
+			purger := service.NewAccessLogPurger(profileRepo, cfg.AuditRetention, logger)
+			workers.Go(func(ctx context.Context) {
+				purger.Run(ctx, cfg.AuditPurgeInterval)
+			})
+		}
+	}
+
+	rateLimits, err := bootstrap.NewRateLimitPolicy(cfg, cacheRepo, logger)
+	if err != nil {
+		return err
+	}
+	loadShedder, err := bootstrap.NewLoadShedder(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Initialize gRPC handlers
+	profileHandler := handler.NewProfileHandler(profiles, logger)
+	lookupHandler := handler.NewLookupHandler(profiles, logger)
+	accessLogHandler := handler.NewAccessLogHandler(accessLog, logger)
 
 	// Start gRPC server
 	lis, err := net.Listen("tcp", ":"+cfg.Port)
 	if err != nil {
-		logger.Error("Failed to listen", "port", cfg.Port, "error", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to listen on port %s: %w", cfg.Port, err)
+	}
+
+	// Interceptors shared by the gRPC port and the Connect/gRPC-Web bridge
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		interceptor.Tracing(),
+		interceptor.RequestID(logger),
+		interceptor.AccessPurpose(),
+		interceptor.BreakGlassGrant(),
+		interceptor.Metrics(),
+		interceptor.Recovery(logger),
+	}
+
+	// Streaming calls are only server reflection and health watches
+	var streamInterceptors []grpc.StreamServerInterceptor
+
+	if tokenVerifier != nil {
+		unaryInterceptors = append(unaryInterceptors, interceptor.Auth(tokenVerifier, logger))
+		streamInterceptors = append(streamInterceptors, interceptor.StreamAuth(tokenVerifier, logger))
+	}
+	// Shedding runs after Auth, so only authenticated callers can claim a
+	// priority
+	if loadShedder != nil {
+		unaryInterceptors = append(unaryInterceptors, interceptor.LoadShed(loadShedder, logger))
 	}
+	if rateLimits != nil {
+		unaryInterceptors = append(unaryInterceptors, interceptor.RateLimit(rateLimits, logger))
+	}
+	unaryInterceptors = append(unaryInterceptors, interceptor.DefaultTimeout(cfg.GRPCDefaultTimeout))
+
+	serverOpts := grpcServerOptions(cfg, unaryInterceptors, streamInterceptors)
 
-	grpcServer := grpc.NewServer()
+	// Serve TLS, optionally mutual, with certificates reloaded on change. The
+	// HTTP listener uses the same certificates.
+	var httpTLS *tls.Config
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		reloader, err := certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, cfg.TLSClientAuth, logger)
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+		httpTLS = reloader.HTTPServerConfig()
+		workers.Go(func(ctx context.Context) {
+			reloader.Run(ctx, cfg.TLSReloadInterval)
+		})
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 	userprofile.RegisterUserProfileServiceServer(grpcServer, profileHandler)
+	profilelookup.RegisterProfileLookupServiceServer(grpcServer, lookupHandler)
+	accesslog.RegisterAccessLogServiceServer(grpcServer, accessLogHandler)
 
-	logger.Info("Starting user profile service", "port", cfg.Port, "env", cfg.Env)
-	if err := grpcServer.Serve(lis); err != nil {
-		logger.Error("Failed to serve gRPC", "error", err)
-		os.Exit(1)
+	// Health reports NOT_SERVING until startup completes
+	healthChecker := health.NewChecker(cfg.HealthCheckInterval, cfg.HealthCheckTimeout, logger,
+		userprofile.UserProfileService_ServiceDesc.ServiceName,
+		profilelookup.ProfileLookupService_ServiceDesc.ServiceName,
+		accesslog.AccessLogService_ServiceDesc.ServiceName)
+	healthChecker.AddDependency("postgres", true, profileRepo.Ping)
+	healthChecker.AddDependency("redis", false, cacheRepo.Ping)
+	healthpb.RegisterHealthServer(grpcServer, healthChecker.Server())
+	workers.Go(healthChecker.Run)
+
+	if cfg.Env != "production" {
+		reflection.Register(grpcServer)
+	}
+
+	// Start HTTP/JSON gateway, with Connect and gRPC-Web for browsers
+	httpMux := http.NewServeMux()
+	httpMux.Handle("/", handler.NewHTTPHandler(profiles, accessLog, breakGlassAPI, tokenVerifier, rateLimits, loadShedder, logger))
+	if cfg.GRPCWebEnabled {
+		paths, connectHandler := handler.NewConnectHandler(profileHandler, lookupHandler, accessLogHandler, unaryInterceptors...)
+		connectHandler = handler.WithCORS(connectHandler, cfg.CORSAllowedOrigins)
+		for _, path := range paths {
+			httpMux.Handle(path, connectHandler)
+		}
+	}
+
+	httpServer := &http.Server{
+		Addr:              ":" + cfg.HTTPPort,
+		Handler:           httpMux,
+		TLSConfig:         httpTLS,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Start admin server with Prometheus metrics
+	prometheus.MustRegister(metrics.NewPoolCollector(profileRepo.Stat))
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("GET /metrics", promhttp.Handler())
+	if cfg.AdminToken != "" {
+		levels := logging.NewLevelController(logLevel, bootstrap.SetupAuditLogger(cfg.Env))
+		adminMux.Handle("/admin/", handler.NewAdminHandler(levels, cfg.AdminToken, cfg.LogLevelTTL))
+	}
+
+	adminServer := &http.Server{
+		Addr:              ":" + cfg.AdminPort,
+		Handler:           adminMux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 3)
+	go func() {
+		logger.Info("Starting admin server", "port", cfg.AdminPort)
+		if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("failed to serve admin HTTP: %w", err)
+		}
+	}()
+	go func() {
+		logger.Info("Starting HTTP gateway", "port", cfg.HTTPPort, "grpc_web", cfg.GRPCWebEnabled, "tls", httpTLS != nil)
+		serve := httpServer.ListenAndServe
+		if httpTLS != nil {
+			serve = func() error { return httpServer.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("failed to serve HTTP: %w", err)
+		}
+	}()
+	go func() {
+		logger.Info("Starting user profile service", "port", cfg.Port, "env", cfg.Env, "tls", cfg.TLSCertFile != "")
+		if err := grpcServer.Serve(lis); err != nil {
+			serveErr <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+
+	healthChecker.SetReady(true)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received")
+	case runErr = <-serveErr:
+	}
+	// A second signal kills the process immediately
+	stop()
+
+	// Fail readiness first so load balancers stop routing new requests
+	healthChecker.Shutdown()
+	if runErr == nil && cfg.ShutdownDrainPeriod > 0 {
+		logger.Info("Draining connections", "period", cfg.ShutdownDrainPeriod)
+		time.Sleep(cfg.ShutdownDrainPeriod)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("HTTP gateway did not shut down cleanly", "error", err)
+	}
+	gracefulStop(shutdownCtx, grpcServer, logger)
+
+	// Keep metrics available until the servers have drained
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Admin server did not shut down cleanly", "error", err)
+	}
+
+	workers.Stop()
+	logger.Info("User profile service stopped")
+
+	return runErr
+}
+
+// grpcServerOptions applies the configured message size, stream and keepalive
+// limits. Zero values keep the gRPC defaults.
+func grpcServerOptions(cfg *config.Config, unaryInterceptors []grpc.UnaryServerInterceptor, streamInterceptors []grpc.StreamServerInterceptor) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.GRPCMaxConnectionIdle,
+			MaxConnectionAge:      cfg.GRPCMaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.GRPCMaxConnectionAgeGrace,
+			Time:                  cfg.GRPCKeepaliveTime,
+			Timeout:               cfg.GRPCKeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.GRPCKeepaliveMinTime,
+			PermitWithoutStream: cfg.GRPCKeepalivePermitWithoutStream,
+		}),
 	}
+
+	if cfg.GRPCMaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.GRPCMaxRecvMsgSize))
+	}
+	if cfg.GRPCMaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.GRPCMaxSendMsgSize))
+	}
+	if cfg.GRPCMaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(cfg.GRPCMaxConcurrentStreams)))
+	}
+
+	return opts
 }
 
-func setupLogger(env string) *slog.Logger {
-	var logger *slog.Logger
+// gracefulStop waits for in-flight RPCs to finish and forcibly closes the
+// remaining ones once ctx expires.
+func gracefulStop(ctx context.Context, server *grpc.Server, logger *slog.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
 
-	switch env {
-	case "development":
-		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		}))
-	default:
-		logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		}))
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("Graceful stop timed out, closing remaining RPCs")
+		server.Stop()
+		<-stopped
 	}
-	return logger
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// workerGroup runs background loops that share one cancellation signal.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+func (g *workerGroup) Go(fn func(ctx context.Context)) {
+	g.wg.Go(func() {
+		fn(g.ctx)
+	})
+}
+
+// Stop cancels all workers and waits for them to return. It is safe to call
+// more than once.
+func (g *workerGroup) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
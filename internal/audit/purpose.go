@@ -0,0 +1,38 @@
+// Package audit records who read personal data, and why, in an append-only
+// access log.
+package audit
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// PurposeHeader is the request metadata in which callers state why they
+// read personal data, e.g. "support ticket 4711".
+const PurposeHeader = "x-access-purpose"
+
+const maxPurposeLength = 200
+
+type purposeKey struct{}
+
+// WithPurpose returns a copy of ctx carrying the stated purpose. Control
+// characters are dropped and long purposes truncated.
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	purpose = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, purpose))
+	if runes := []rune(purpose); len(runes) > maxPurposeLength {
+		purpose = string(runes[:maxPurposeLength])
+	}
+	return context.WithValue(ctx, purposeKey{}, purpose)
+}
+
+// Purpose returns the purpose stored in ctx, if any.
+func Purpose(ctx context.Context) string {
+	purpose, _ := ctx.Value(purposeKey{}).(string)
+	return purpose
+}
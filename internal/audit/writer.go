@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnavailable means a record could not be queued, because the access log
+// is not keeping up or records cannot be written.
+var ErrUnavailable = errors.New("access log unavailable")
+
+const (
+	// writeAttempts is how often a batch is written before the writer refuses
+	// new records, and before a batch is given up on shutdown.
+	writeAttempts = 3
+	// maxRetryDelay caps the wait between writes of a failing batch.
+	maxRetryDelay = 5 * time.Second
+	// writeTimeout bounds each batch write, which outlives cancellation of
+	// Run so that shutdown does not lose records.
+	writeTimeout = 10 * time.Second
+)
+
+// Sink stores batches of access records.
+type Sink interface {
+	AppendAccessRecords(ctx context.Context, records []models.AccessRecord) error
+}
+
+type WriterOptions struct {
+	// BufferSize is the number of records queued before Record blocks.
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Writer queues access records and writes them to the sink in batches, so
+// that reads do not wait for a database round trip. A full queue blocks
+// readers instead of dropping records, and a batch that cannot be written is
+// retried until it is, refusing new records meanwhile.
+//
+// Records are still lost when the process crashes with records queued, or
+// when a batch cannot be written during shutdown. Both are counted in
+// audit_records_lost_total; audit_writer_failing is 1 while reads are refused.
+type Writer struct {
+	sink       Sink
+	opts       WriterOptions
+	records    chan models.AccessRecord
+	retryDelay time.Duration
+	failing    atomic.Bool
+	logger     *slog.Logger
+}
+
+func NewWriter(sink Sink, opts WriterOptions, logger *slog.Logger) (*Writer, error) {
+	if opts.BufferSize <= 0 || opts.BatchSize <= 0 {
+		return nil, fmt.Errorf("buffer and batch size must be positive, got %d and %d", opts.BufferSize, opts.BatchSize)
+	}
+	if opts.FlushInterval <= 0 {
+		return nil, fmt.Errorf("flush interval must be positive, got %s", opts.FlushInterval)
+	}
+
+	return &Writer{
+		sink:       sink,
+		opts:       opts,
+		records:    make(chan models.AccessRecord, opts.BufferSize),
+		retryDelay: 200 * time.Millisecond,
+		logger:     logger,
+	}, nil
+}
+
+// Record queues rec, waiting for room until ctx is done. It fails at once
+// while queued records cannot be written.
+func (w *Writer) Record(ctx context.Context, rec models.AccessRecord) error {
+	if w.failing.Load() {
+		return fmt.Errorf("%w: records cannot be written", ErrUnavailable)
+	}
+
+	select {
+	case w.records <- rec:
+		metrics.AuditRecords.WithLabelValues(rec.Method).Inc()
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrUnavailable, ctx.Err())
+	}
+}
+
+// Run writes queued records until ctx is cancelled, then flushes what is
+// left.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AccessRecord, 0, w.opts.BatchSize)
+	for {
+		select {
+		case rec := <-w.records:
+			batch = append(batch, rec)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ctx.Done():
+			w.drain(ctx, batch)
+			return
+		}
+	}
+}
+
+func (w *Writer) drain(ctx context.Context, batch []models.AccessRecord) {
+	for {
+		select {
+		case rec := <-w.records:
+			batch = append(batch, rec)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		default:
+			if len(batch) > 0 {
+				w.flush(ctx, batch)
+			}
+			return
+		}
+	}
+}
+
+// flush writes batch, retrying until it succeeds. After writeAttempts
+// failures, new records are refused until the batch is written. Once ctx is
+// done the batch is given up after writeAttempts, so that shutdown ends.
+func (w *Writer) flush(ctx context.Context, batch []models.AccessRecord) {
+	for attempt := 0; ; attempt++ {
+		if attempt >= writeAttempts && ctx.Err() != nil {
+			metrics.AuditRecordsLost.Add(float64(len(batch)))
+			w.logger.Error("Gave up writing access records on shutdown", "records", len(batch))
+			return
+		}
+		if attempt > 0 {
+			time.Sleep(min(time.Duration(attempt)*w.retryDelay, maxRetryDelay))
+		}
+
+		writeCtx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := w.sink.AppendAccessRecords(writeCtx, batch)
+		cancel()
+		if err == nil {
+			if w.failing.Swap(false) {
+				metrics.AuditWriterFailing.Set(0)
+				w.logger.Info("Access records written again, accepting reads", "records", len(batch))
+			}
+			return
+		}
+
+		if attempt == writeAttempts-1 && !w.failing.Swap(true) {
+			metrics.AuditWriterFailing.Set(1)
+			w.logger.Error("Failed to write access records, refusing reads until they are written", "records", len(batch), "error", err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink fails writes while failing is set and keeps the records it
+// stored.
+type fakeSink struct {
+	mu       sync.Mutex
+	failing  bool
+	attempts int
+	stored   []models.AccessRecord
+}
+
+func (s *fakeSink) AppendAccessRecords(_ context.Context, records []models.AccessRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.failing {
+		return errors.New("database unavailable")
+	}
+	s.stored = append(s.stored, records...)
+	return nil
+}
+
+func (s *fakeSink) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *fakeSink) state() (attempts, stored int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, len(s.stored)
+}
+
+func newTestWriter(t *testing.T, sink Sink) *Writer {
+	t.Helper()
+	w, err := NewWriter(sink, WriterOptions{BufferSize: 10, BatchSize: 1, FlushInterval: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.retryDelay = time.Millisecond
+	return w
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWriterRefusesRecordsWhileWritesFail(t *testing.T) {
+	sink := &fakeSink{failing: true}
+	w := newTestWriter(t, sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if err := w.Record(ctx, models.AccessRecord{Method: "GetUserProfile"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	waitFor(t, "the writer to fail", w.failing.Load)
+	if v := testutil.ToFloat64(metrics.AuditWriterFailing); v != 1 {
+		t.Errorf("audit_writer_failing = %g, want 1", v)
+	}
+
+	if err := w.Record(ctx, models.AccessRecord{Method: "GetUserProfile"}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Record() while failing error = %v, want %v", err, ErrUnavailable)
+	}
+
+	// The failed batch is kept and written once the sink recovers
+	sink.setFailing(false)
+	waitFor(t, "the writer to recover", func() bool { return !w.failing.Load() })
+	if _, stored := sink.state(); stored != 1 {
+		t.Errorf("stored %d records, want the failed batch", stored)
+	}
+	if v := testutil.ToFloat64(metrics.AuditWriterFailing); v != 0 {
+		t.Errorf("audit_writer_failing = %g, want 0", v)
+	}
+	if err := w.Record(ctx, models.AccessRecord{Method: "GetUserProfile"}); err != nil {
+		t.Errorf("Record() after recovery error = %v", err)
+	}
+}
+
+func TestWriterFlushesOnShutdown(t *testing.T) {
+	sink := &fakeSink{}
+	w, err := NewWriter(sink, WriterOptions{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if err := w.Record(context.Background(), models.AccessRecord{Method: "GetUserProfile"}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Run(ctx)
+
+	if _, stored := sink.state(); stored != 3 {
+		t.Errorf("stored %d records, want 3", stored)
+	}
+}
+
+func TestWriterGivesUpOnShutdown(t *testing.T) {
+	sink := &fakeSink{failing: true}
+	w := newTestWriter(t, sink)
+
+	if err := w.Record(context.Background(), models.AccessRecord{Method: "GetUserProfile"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	lost := testutil.ToFloat64(metrics.AuditRecordsLost)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Run(ctx)
+
+	if attempts, _ := sink.state(); attempts != writeAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, writeAttempts)
+	}
+	if v := testutil.ToFloat64(metrics.AuditRecordsLost) - lost; v != 1 {
+		t.Errorf("audit_records_lost_total grew by %g, want 1", v)
+	}
+}
@@ -0,0 +1,208 @@
+// Package auth verifies caller JWTs and identifies the caller of a request.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Roles assigned to tokens without a roles claim.
+const (
+	RoleUser    = "user"
+	RoleService = "service"
+)
+
+// validMethods excludes HMAC so a public key can never be used as a shared
+// secret.
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Scopes  []string
+	// Roles come from the roles claim. Tokens without one are end users, or
+	// services when they carry the service scope.
+	Roles []string
+	// Service is set for service-to-service tokens.
+	Service bool
+	// CertIdentity is the verified TLS client certificate identity of the
+	// connection, if any.
+	CertIdentity string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller authenticated for ctx, or nil when
+// authentication is disabled.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// VerifierConfig lists where signing keys come from and which claims tokens
+// must carry. At least one of JWKSFile and PublicKeyFiles is required.
+type VerifierConfig struct {
+	JWKSFile       string
+	PublicKeyFiles []string
+	Issuer         string
+	Audience       string
+	// ServiceScope marks service-to-service tokens.
+	ServiceScope string
+}
+
+type Verifier struct {
+	keyfunc      jwt.Keyfunc
+	parser       *jwt.Parser
+	serviceScope string
+}
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	var keys jwt.VerificationKeySet
+
+	for _, path := range cfg.PublicKeyFiles {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+
+	var jwks keyfunc.Keyfunc
+	if cfg.JWKSFile != "" {
+		raw, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		if jwks, err = keyfunc.NewJWKSetJSON(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+		}
+	}
+
+	if jwks == nil && len(keys.Keys) == 0 {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Verifier{
+		keyfunc: func(token *jwt.Token) (any, error) {
+			// Tokens with a key ID are matched against the JWKS, all other
+			// tokens are tried against every static key
+			if _, ok := token.Header["kid"]; ok && jwks != nil {
+				return jwks.Keyfunc(token)
+			}
+			if len(keys.Keys) == 0 {
+				return nil, errors.New("token has no key ID")
+			}
+			return keys, nil
+		},
+		parser:       jwt.NewParser(opts...),
+		serviceScope: cfg.ServiceScope,
+	}, nil
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space-separated OAuth 2.0 form, Scp the array form used
+	// by some identity providers.
+	Scope string   `json:"scope,omitempty"`
+	Scp   []string `json:"scp,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Verify checks the signature and registered claims of a raw JWT and returns
+// the caller it identifies.
+func (v *Verifier) Verify(raw string) (*Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(raw, &c, v.keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	scopes := append(strings.Fields(c.Scope), c.Scp...)
+	service := v.serviceScope != "" && slices.Contains(scopes, v.serviceScope)
+
+	roles := c.Roles
+	if len(roles) == 0 {
+		roles = []string{RoleUser}
+		if service {
+			roles = []string{RoleService}
+		}
+	}
+
+	return &Principal{
+		Subject: c.Subject,
+		Scopes:  scopes,
+		Roles:   roles,
+		Service: service,
+	}, nil
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) (string, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrMissingToken
+	}
+	return token, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		return key, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %s: %w", path, err)
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "user-profile-service"
+)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writePublicKey stores the public half of key as a PEM file and returns its
+// path.
+func writePublicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestVerifier(t *testing.T, key *ecdsa.PrivateKey) *Verifier {
+	t.Helper()
+	verifier, err := NewVerifier(VerifierConfig{
+		PublicKeyFiles: []string{writePublicKey(t, key)},
+		Issuer:         testIssuer,
+		Audience:       testAudience,
+		ServiceScope:   "profiles:service",
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return verifier
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	key := newTestKey(t)
+	verifier := newTestVerifier(t, key)
+
+	principal, err := verifier.Verify(sign(t, key, validClaims()))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if principal.Subject != "user-1" {
+		t.Errorf("subject = %q, want user-1", principal.Subject)
+	}
+	if principal.Service || !slices.Equal(principal.Roles, []string{RoleUser}) {
+		t.Errorf("principal = %+v, want an end user", principal)
+	}
+}
+
+func TestVerifyRoles(t *testing.T) {
+	key := newTestKey(t)
+	verifier := newTestVerifier(t, key)
+
+	tests := []struct {
+		name        string
+		claims      map[string]any
+		wantRoles   []string
+		wantService bool
+	}{
+		{"service scope", map[string]any{"scope": "profiles:read profiles:service"}, []string{RoleService}, true},
+		{"scp array", map[string]any{"scp": []string{"profiles:service"}}, []string{RoleService}, true},
+		{"roles claim", map[string]any{"roles": []string{"support"}}, []string{"support"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+
+			principal, err := verifier.Verify(sign(t, key, claims))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if !slices.Equal(principal.Roles, tt.wantRoles) || principal.Service != tt.wantService {
+				t.Errorf("roles = %v, service = %t, want %v, %t", principal.Roles, principal.Service, tt.wantRoles, tt.wantService)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	key := newTestKey(t)
+	verifier := newTestVerifier(t, key)
+
+	with := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"expired":        sign(t, key, with("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":      sign(t, key, with("exp", nil)),
+		"wrong issuer":   sign(t, key, with("iss", "https://evil.example.com")),
+		"wrong audience": sign(t, key, with("aud", "another-service")),
+		"no subject":     sign(t, key, with("sub", nil)),
+		"wrong key":      sign(t, newTestKey(t), validClaims()),
+		"hmac":           hmacToken,
+		"malformed":      "not-a-jwt",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			principal, err := verifier.Verify(token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify = %+v, %v, want ErrInvalidToken", principal, err)
+			}
+		})
+	}
+}
+
+func TestVerifyAllowsClockSkew(t *testing.T) {
+	key := newTestKey(t)
+	verifier := newTestVerifier(t, key)
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+
+	if _, err := verifier.Verify(sign(t, key, claims)); err != nil {
+		t.Errorf("Verify of a token expired within the leeway: %v", err)
+	}
+}
+
+func TestNewVerifierRequiresKeys(t *testing.T) {
+	if _, err := NewVerifier(VerifierConfig{}); err == nil {
+		t.Error("NewVerifier without keys succeeded")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]struct {
+		header  string
+		want    string
+		wantErr bool
+	}{
+		"bearer":            {header: "Bearer abc", want: "abc"},
+		"lower case":        {header: "bearer abc", want: "abc"},
+		"empty":             {header: "", wantErr: true},
+		"other scheme":      {header: "Basic abc", wantErr: true},
+		"no token":          {header: "Bearer ", wantErr: true},
+		"missing separator": {header: "Bearerabc", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := BearerToken(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMissingToken) {
+					t.Errorf("BearerToken(%q) = %q, %v, want ErrMissingToken", tt.header, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("BearerToken(%q) = %q, %v, want %q", tt.header, got, err, tt.want)
+			}
+		})
+	}
+}
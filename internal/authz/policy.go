@@ -0,0 +1,220 @@
+// Package authz holds the role-based access policy that decides which
+// profile operations and fields each caller role may use.
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Operations that a role can be granted.
+const (
+	MethodGet    = "GetUserProfile"
+	MethodCreate = "CreateUserProfile"
+	MethodUpdate = "UpdateUserProfile"
+	MethodDelete = "DeleteUserProfile"
+
+	MethodFindByPhone   = "FindProfileByPhone"
+	MethodFindByLicense = "FindProfileByLicense"
+
+	// MethodQueryAccessLog reads the access log of a profile. It is only
+	// served over HTTP.
+	MethodQueryAccessLog = "QueryAccessLog"
+	// MethodRequestBreakGlass issues a break-glass grant for a profile. It is
+	// only served over HTTP.
+	MethodRequestBreakGlass = "RequestBreakGlass"
+	// MethodRevokeBreakGlass revokes anyone's break-glass grant. Callers that
+	// may request grants can revoke their own without it. It is only served
+	// over HTTP.
+	MethodRevokeBreakGlass = "RevokeBreakGlass"
+)
+
+// Scopes limit which profiles a role may access.
+const (
+	ScopeOwn = "own"
+	ScopeAny = "any"
+)
+
+const wildcard = "*"
+
+var knownMethods = []string{MethodGet, MethodCreate, MethodUpdate, MethodDelete, MethodFindByPhone, MethodFindByLicense, MethodQueryAccessLog, MethodRequestBreakGlass, MethodRevokeBreakGlass}
+
+// identityFields are returned to every caller that may read a profile.
+var identityFields = []string{"id", "user_id", "created_at", "updated_at"}
+
+// RolePolicy lists what one role may do. Fields are JSON field names of a
+// profile; "*" grants every method or field.
+type RolePolicy struct {
+	Methods     []string `json:"methods"`
+	Scope       string   `json:"scope"`
+	ReadFields  []string `json:"read_fields"`
+	WriteFields []string `json:"write_fields"`
+}
+
+type Policy struct {
+	Roles map[string]RolePolicy `json:"roles"`
+	// Identities grants roles to callers by their verified TLS client
+	// certificate identity.
+	Identities map[string][]string `json:"identities"`
+}
+
+// DefaultPolicy lets end users manage their own profile and services manage
+// any profile, with access to every field. Only the compliance role may
+// delete profiles and revoke break-glass grants.
+func DefaultPolicy() *Policy {
+	all := []string{wildcard}
+	manage := []string{MethodGet, MethodCreate, MethodUpdate, MethodFindByPhone, MethodFindByLicense}
+	return &Policy{Roles: map[string]RolePolicy{
+		"user":       {Methods: slices.Concat(manage, []string{MethodQueryAccessLog}), Scope: ScopeOwn, ReadFields: all, WriteFields: all},
+		"service":    {Methods: manage, Scope: ScopeAny, ReadFields: all, WriteFields: all},
+		"compliance": {Methods: []string{MethodGet, MethodDelete, MethodRevokeBreakGlass}, Scope: ScopeAny, ReadFields: all},
+	}}
+}
+
+// LoadPolicy reads and validates a JSON policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse RBAC policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RBAC policy: %w", err)
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) Validate() error {
+	fields := ProfileFields()
+
+	for identity, roles := range p.Identities {
+		for _, role := range roles {
+			if _, ok := p.Roles[role]; !ok {
+				return fmt.Errorf("identity %q: unknown role %q", identity, role)
+			}
+		}
+	}
+
+	for name, role := range p.Roles {
+		for _, m := range role.Methods {
+			if m != wildcard && !slices.Contains(knownMethods, m) {
+				return fmt.Errorf("role %q: unknown method %q", name, m)
+			}
+		}
+		if role.Scope != ScopeOwn && role.Scope != ScopeAny {
+			return fmt.Errorf("role %q: scope must be %q or %q", name, ScopeOwn, ScopeAny)
+		}
+		for _, f := range slices.Concat(role.ReadFields, role.WriteFields) {
+			if f != wildcard && !slices.Contains(fields, f) {
+				return fmt.Errorf("role %q: unknown field %q", name, f)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Grant is the combined permission of all roles held by a caller.
+type Grant struct {
+	methods     map[string]bool
+	anyUser     bool
+	readFields  map[string]bool
+	writeFields map[string]bool
+}
+
+// Grant merges the permissions of roles and of the roles bound to the client
+// certificate identity. Unknown roles grant nothing.
+func (p *Policy) Grant(roles []string, certIdentity string) Grant {
+	g := Grant{
+		methods:     map[string]bool{},
+		readFields:  map[string]bool{},
+		writeFields: map[string]bool{},
+	}
+
+	if certIdentity != "" {
+		roles = slices.Concat(roles, p.Identities[certIdentity])
+	}
+
+	for _, name := range roles {
+		role, ok := p.Roles[name]
+		if !ok {
+			continue
+		}
+		for _, m := range role.Methods {
+			g.methods[m] = true
+		}
+		for _, f := range role.ReadFields {
+			g.readFields[f] = true
+		}
+		for _, f := range role.WriteFields {
+			g.writeFields[f] = true
+		}
+		g.anyUser = g.anyUser || role.Scope == ScopeAny
+	}
+
+	return g
+}
+
+func (g Grant) CanCall(method string) bool {
+	return g.methods[wildcard] || g.methods[method]
+}
+
+// CanAccessUser reports whether the grant covers the profile of userID for a
+// caller identified by subject.
+func (g Grant) CanAccessUser(subject, userID string) bool {
+	return g.anyUser || subject == userID
+}
+
+// CanAccessAnyUser reports whether the grant covers every profile.
+func (g Grant) CanAccessAnyUser() bool {
+	return g.anyUser
+}
+
+func (g Grant) CanRead(field string) bool {
+	return g.readFields[wildcard] || g.readFields[field] || slices.Contains(identityFields, field)
+}
+
+func (g Grant) CanWrite(field string) bool {
+	return g.writeFields[wildcard] || g.writeFields[field]
+}
+
+// IsIdentityField reports whether field identifies a profile rather than
+// holding personal data, and is readable by every caller.
+func IsIdentityField(field string) bool {
+	return slices.Contains(identityFields, field)
+}
+
+// ProfileFields lists the JSON field names of a profile.
+func ProfileFields() []string {
+	t := reflect.TypeFor[models.UserProfile]()
+	fields := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		if name := JSONName(t.Field(i)); name != "" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// JSONName returns the JSON name of a struct field, or "" when it is not
+// serialized.
+func JSONName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package authz
+
+import "testing"
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("default policy is invalid: %v", err)
+	}
+
+	tests := []struct {
+		role    string
+		method  string
+		allowed bool
+	}{
+		{"user", MethodGet, true},
+		{"user", MethodUpdate, true},
+		{"user", MethodQueryAccessLog, true},
+		{"user", MethodDelete, false},
+		{"user", MethodRequestBreakGlass, false},
+		{"service", MethodCreate, true},
+		{"service", MethodDelete, false},
+		{"compliance", MethodDelete, true},
+		{"compliance", MethodUpdate, false},
+		{"compliance", MethodRevokeBreakGlass, true},
+		{"service", MethodRevokeBreakGlass, false},
+	}
+	for _, tt := range tests {
+		if got := policy.Grant([]string{tt.role}, "").CanCall(tt.method); got != tt.allowed {
+			t.Errorf("%s may call %s = %t, want %t", tt.role, tt.method, got, tt.allowed)
+		}
+	}
+}
+
+func TestExamplePolicyLimitsDeletes(t *testing.T) {
+	policy, err := LoadPolicy("../../rbac_policy.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range policy.Roles {
+		if got := policy.Grant([]string{name}, "").CanCall(MethodDelete); got != (name == "compliance") {
+			t.Errorf("%s may delete = %t", name, got)
+		}
+	}
+}
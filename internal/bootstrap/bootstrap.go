@@ -0,0 +1,186 @@
+// Package bootstrap builds the shared dependencies of the server and the
+// profilectl command from configuration.
+package bootstrap
+
+import (
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/config"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/loadshed"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/ratelimit"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"github.com/Brrocat/user-profile-service/internal/repository/redis"
+	"log/slog"
+	"os"
+)
+
+// SetupLogger builds the server logger. Production logs are JSON, other
+// environments use text. level overrides the default of debug in development
+// and info elsewhere, and the returned LevelVar changes it at runtime.
+// Personal data is redacted in every environment.
+func SetupLogger(env, level string) (*slog.Logger, *slog.LevelVar, error) {
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(slog.LevelInfo)
+	if env == "development" {
+		levelVar.Set(slog.LevelDebug)
+	}
+	if level != "" {
+		var lvl slog.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", level, err)
+		}
+		levelVar.Set(lvl)
+	}
+
+	return slog.New(newLogHandler(env, levelVar)), levelVar, nil
+}
+
+// SetupAuditLogger builds a logger for audit records. It always logs at info
+// so records survive a runtime level change.
+func SetupAuditLogger(env string) *slog.Logger {
+	return slog.New(newLogHandler(env, slog.LevelInfo)).With("log_type", "audit")
+}
+
+func newLogHandler(env string, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch env {
+	case "production":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return logging.NewRedactHandler(handler)
+}
+
+// NewFieldCipher loads the keyring used to encrypt personal data at rest. It
+// returns nil when encryption is disabled.
+func NewFieldCipher(cfg *config.Config) (*encryption.FieldCipher, error) {
+	if cfg.EncryptionKeyringFile == "" {
+		return nil, nil
+	}
+	return encryption.LoadKeyring(cfg.EncryptionKeyringFile)
+}
+
+func NewProfileRepository(cfg *config.Config) (*postgres.ProfileRepository, error) {
+	cipher, err := NewFieldCipher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var index *encryption.BlindIndex
+	if cfg.EncryptionKeyringFile != "" {
+		if index, err = encryption.LoadBlindIndex(cfg.EncryptionKeyringFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return postgres.NewProfileRepository(cfg.DatabaseURL, cipher, index)
+}
+
+func NewCacheRepository(cfg *config.Config) (*redis.CacheRepository, error) {
+	cacheCodec, err := redis.ParseCodec(cfg.CacheCodec)
+	if err != nil {
+		return nil, err
+	}
+
+	cipher, err := NewFieldCipher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheOpts := redis.CacheOptions{
+		TTL:         cfg.CacheTTL,
+		NegativeTTL: cfg.CacheNegativeTTL,
+		BatchTTL:    cfg.CacheBatchTTL,
+		Jitter:      cfg.CacheTTLJitter,
+		KeyPrefix:   cfg.CacheKeyPrefix,
+		Codec:       cacheCodec,
+		Compress:    cfg.CacheCompress,
+		Cipher:      cipher,
+	}
+
+	redisCfg := redis.ClientConfig{
+		Mode:                  cfg.RedisMode,
+		URL:                   cfg.RedisURL,
+		Addrs:                 cfg.RedisAddrs,
+		MasterName:            cfg.RedisMasterName,
+		Username:              cfg.RedisUsername,
+		Password:              cfg.RedisPassword,
+		SentinelUsername:      cfg.RedisSentinelUsername,
+		SentinelPassword:      cfg.RedisSentinelPassword,
+		DB:                    cfg.RedisDB,
+		TLS:                   cfg.RedisTLS,
+		TLSCAFile:             cfg.RedisTLSCAFile,
+		TLSServerName:         cfg.RedisTLSServerName,
+		TLSInsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		ReadFromReplica:       cfg.RedisReadFromReplica,
+	}
+
+	return redis.NewCacheRepository(redisCfg, cacheOpts)
+}
+
+// NewRateLimitPolicy builds the per-caller and per-user rate limits. It
+// returns nil when rate limiting is disabled. The redis backend shares
+// buckets across replicas and falls back to local buckets while Redis is
+// unreachable.
+func NewRateLimitPolicy(cfg *config.Config, cache *redis.CacheRepository, logger *slog.Logger) (*ratelimit.Policy, error) {
+	if !cfg.RateLimitEnabled {
+		return nil, nil
+	}
+
+	callerLimits, err := ratelimit.ParseLimits(cfg.RateLimitCallerLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_CALLER_LIMITS: %w", err)
+	}
+	userLimits, err := ratelimit.ParseLimits(cfg.RateLimitUserLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_USER_LIMITS: %w", err)
+	}
+
+	var limiter ratelimit.Limiter
+	switch cfg.RateLimitBackend {
+	case "local":
+		limiter = ratelimit.NewLocalLimiter()
+	case "redis":
+		limiter = ratelimit.NewFallbackLimiter(
+			ratelimit.NewRedisLimiter(cache.Client(), cfg.CacheKeyPrefix),
+			ratelimit.NewLocalLimiter(),
+			func(err error) {
+				metrics.RateLimitErrors.Inc()
+				logger.Warn("Redis rate limiter unavailable, using local limits", "error", err)
+			},
+		)
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BACKEND %q: must be local or redis", cfg.RateLimitBackend)
+	}
+
+	return ratelimit.NewPolicy(limiter, callerLimits, userLimits), nil
+}
+
+// NewLoadShedder builds the adaptive concurrency limiter and reports its
+// state in metrics. It returns nil when load shedding is disabled.
+func NewLoadShedder(cfg *config.Config) (*loadshed.Limiter, error) {
+	if !cfg.LoadShedEnabled {
+		return nil, nil
+	}
+
+	limiter, err := loadshed.NewLimiter(loadshed.Options{
+		InitialLimit:  cfg.LoadShedInitialLimit,
+		MinLimit:      cfg.LoadShedMinLimit,
+		MaxLimit:      cfg.LoadShedMaxLimit,
+		TargetLatency: cfg.LoadShedTargetLatency,
+		OnChange: func(limit float64, inflight int) {
+			metrics.ConcurrencyLimit.Set(limit)
+			metrics.ConcurrencyInflight.Set(float64(inflight))
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid load shedding configuration: %w", err)
+	}
+	return limiter, nil
+}
@@ -0,0 +1,23 @@
+// Package breakglass carries break-glass grant IDs through requests and
+// announces the use of grants.
+package breakglass
+
+import "context"
+
+// GrantHeader is the request metadata naming the break-glass grant a read
+// is made under.
+const GrantHeader = "x-break-glass-grant"
+
+type grantKey struct{}
+
+// WithGrantID returns a copy of ctx carrying the grant ID the caller claims.
+// The grant is only checked by the authorization layer.
+func WithGrantID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, grantKey{}, id)
+}
+
+// GrantID returns the grant ID stored in ctx, if any.
+func GrantID(ctx context.Context) string {
+	id, _ := ctx.Value(grantKey{}).(string)
+	return id
+}
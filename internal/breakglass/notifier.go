@@ -0,0 +1,144 @@
+package breakglass
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	EventGranted = "break_glass.granted"
+	EventUsed    = "break_glass.used"
+	EventRevoked = "break_glass.revoked"
+)
+
+const (
+	webhookTimeout = 5 * time.Second
+	// webhookQueueSize is the number of events waiting for the webhook
+	// before new ones are dropped. Every event is in the audit log anyway.
+	webhookQueueSize = 100
+)
+
+// Event announces that a grant was issued, used or revoked. It names the profile but
+// carries none of its data.
+type Event struct {
+	Type      string    `json:"type"`
+	GrantID   string    `json:"grant_id"`
+	Subject   string    `json:"subject"`
+	UserID    string    `json:"user_id"`
+	Ticket    string    `json:"ticket"`
+	Reason    string    `json:"reason"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RequestID string    `json:"request_id,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Notifier writes every event to the audit log and, with a webhook URL, posts
+// it as JSON so that security can be alerted. Webhook calls are queued for
+// Run and do not delay the request.
+type Notifier struct {
+	auditLogger *slog.Logger
+	webhookURL  string
+	client      *http.Client
+	events      chan Event
+	logger      *slog.Logger
+}
+
+func NewNotifier(auditLogger *slog.Logger, webhookURL string, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		auditLogger: auditLogger,
+		webhookURL:  webhookURL,
+		client:      &http.Client{Timeout: webhookTimeout},
+		events:      make(chan Event, webhookQueueSize),
+		logger:      logger,
+	}
+}
+
+func (n *Notifier) Notify(event Event) {
+	n.auditLogger.Warn("Break-glass access",
+		"event", event.Type,
+		"grant_id", event.GrantID,
+		"subject", event.Subject,
+		"user_id", event.UserID,
+		"ticket", event.Ticket,
+		"reason", event.Reason,
+		"expires_at", event.ExpiresAt,
+		"request_id", event.RequestID,
+	)
+
+	if n.webhookURL == "" {
+		return
+	}
+	select {
+	case n.events <- event:
+	default:
+		metrics.BreakGlassWebhooksLost.Inc()
+		n.logger.Error("Break-glass notification queue is full, dropping webhook call",
+			"event", event.Type, "grant_id", event.GrantID)
+	}
+}
+
+// Run posts queued events to the webhook until ctx is cancelled, then posts
+// what is left.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case event := <-n.events:
+			n.send(event)
+		case <-ctx.Done():
+			n.drain()
+			return
+		}
+	}
+}
+
+func (n *Notifier) drain() {
+	for {
+		select {
+		case event := <-n.events:
+			n.send(event)
+		default:
+			return
+		}
+	}
+}
+
+func (n *Notifier) send(event Event) {
+	if err := n.post(event); err != nil {
+		metrics.BreakGlassWebhooksLost.Inc()
+		n.logger.Error("Failed to send break-glass notification",
+			"event", event.Type, "grant_id", event.GrantID, "error", err)
+	}
+}
+
+func (n *Notifier) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package breakglass
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhook records the events posted to it and answers with status.
+type webhook struct {
+	mu     sync.Mutex
+	status int
+	events []Event
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var event Event
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(rw, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = append(w.events, event)
+	rw.WriteHeader(w.status)
+}
+
+func (w *webhook) received() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Event(nil), w.events...)
+}
+
+func newTestNotifier(t *testing.T, status int) (*Notifier, *webhook, *bytes.Buffer) {
+	t.Helper()
+	hook := &webhook{status: status}
+	srv := httptest.NewServer(hook)
+	t.Cleanup(srv.Close)
+
+	var auditLog bytes.Buffer
+	n := NewNotifier(slog.New(slog.NewTextHandler(&auditLog, nil)), srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return n, hook, &auditLog
+}
+
+func testEvent(grantID string) Event {
+	return Event{
+		Type:      EventUsed,
+		GrantID:   grantID,
+		Subject:   "oncall",
+		UserID:    "7d5d6bde-6a3c-4a8e-9a51-3f1c0c9b2f10",
+		Ticket:    "INC-1",
+		Reason:    "outage",
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+		Time:      time.Now().UTC(),
+	}
+}
+
+// runUntilCancelled runs n until the returned function cancels it and waits
+// for Run to return.
+func runUntilCancelled(n *Notifier) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestNotifierQueuesWebhookCalls(t *testing.T) {
+	n, hook, auditLog := newTestNotifier(t, http.StatusNoContent)
+
+	event := testEvent("grant-1")
+	n.Notify(event)
+
+	// Notify only queues the call, the audit log line is written at once
+	if got := hook.received(); len(got) != 0 {
+		t.Errorf("webhook received %d events before Run, want 0", len(got))
+	}
+	if !strings.Contains(auditLog.String(), "grant_id=grant-1") {
+		t.Errorf("audit log = %q, want the grant", auditLog.String())
+	}
+
+	stop := runUntilCancelled(n)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(hook.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	got := hook.received()
+	if len(got) != 1 {
+		t.Fatalf("webhook received %d events, want 1", len(got))
+	}
+	if got[0].Type != event.Type || got[0].GrantID != event.GrantID || got[0].Ticket != event.Ticket || !got[0].ExpiresAt.Equal(event.ExpiresAt) {
+		t.Errorf("webhook received %+v, want %+v", got[0], event)
+	}
+}
+
+func TestNotifierWithoutWebhook(t *testing.T) {
+	var auditLog bytes.Buffer
+	n := NewNotifier(slog.New(slog.NewTextHandler(&auditLog, nil)), "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	n.Notify(testEvent("grant-1"))
+
+	if len(n.events) != 0 {
+		t.Errorf("queued %d webhook calls, want 0", len(n.events))
+	}
+	if !strings.Contains(auditLog.String(), "grant_id=grant-1") {
+		t.Errorf("audit log = %q, want the grant", auditLog.String())
+	}
+}
+
+func TestNotifierDropsEventsWhenQueueIsFull(t *testing.T) {
+	n, hook, auditLog := newTestNotifier(t, http.StatusOK)
+
+	lost := testutil.ToFloat64(metrics.BreakGlassWebhooksLost)
+	for range webhookQueueSize + 2 {
+		n.Notify(testEvent("grant-1"))
+	}
+
+	if v := testutil.ToFloat64(metrics.BreakGlassWebhooksLost) - lost; v != 2 {
+		t.Errorf("break_glass_webhooks_lost_total grew by %g, want 2", v)
+	}
+	// Dropped events are still in the audit log
+	if lines := strings.Count(auditLog.String(), "\n"); lines != webhookQueueSize+2 {
+		t.Errorf("audit log has %d lines, want %d", lines, webhookQueueSize+2)
+	}
+
+	runUntilCancelled(n)()
+	if got := len(hook.received()); got != webhookQueueSize {
+		t.Errorf("webhook received %d events, want %d", got, webhookQueueSize)
+	}
+}
+
+func TestNotifierCountsFailedCalls(t *testing.T) {
+	tests := []struct {
+		status int
+		lost   float64
+	}{
+		{http.StatusOK, 0},
+		{http.StatusAccepted, 0},
+		{http.StatusFound, 2},
+		{http.StatusBadRequest, 2},
+		{http.StatusServiceUnavailable, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			n, hook, _ := newTestNotifier(t, tt.status)
+
+			lost := testutil.ToFloat64(metrics.BreakGlassWebhooksLost)
+			n.Notify(testEvent("grant-1"))
+			n.Notify(testEvent("grant-2"))
+			runUntilCancelled(n)()
+
+			// A failed call does not stop later ones
+			if got := len(hook.received()); got != 2 {
+				t.Errorf("webhook received %d events, want 2", got)
+			}
+			if v := testutil.ToFloat64(metrics.BreakGlassWebhooksLost) - lost; v != tt.lost {
+				t.Errorf("break_glass_webhooks_lost_total grew by %g, want %g", v, tt.lost)
+			}
+		})
+	}
+}
+
+func TestNotifierCountsUnreachableWebhook(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	n := NewNotifier(slog.New(slog.NewTextHandler(io.Discard, nil)), srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	lost := testutil.ToFloat64(metrics.BreakGlassWebhooksLost)
+	n.Notify(testEvent("grant-1"))
+	runUntilCancelled(n)()
+
+	if v := testutil.ToFloat64(metrics.BreakGlassWebhooksLost) - lost; v != 1 {
+		t.Errorf("break_glass_webhooks_lost_total grew by %g, want 1", v)
+	}
+}
+
+func TestNotifierDrainsQueueOnShutdown(t *testing.T) {
+	n, hook, _ := newTestNotifier(t, http.StatusOK)
+
+	for _, id := range []string{"grant-1", "grant-2", "grant-3"} {
+		n.Notify(testEvent(id))
+	}
+
+	// Run returns only once every queued event is posted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n.Run(ctx)
+
+	got := hook.received()
+	if len(got) != 3 {
+		t.Fatalf("webhook received %d events, want 3", len(got))
+	}
+	for i, id := range []string{"grant-1", "grant-2", "grant-3"} {
+		if got[i].GrantID != id {
+			t.Errorf("event %d grant = %q, want %q", i, got[i].GrantID, id)
+		}
+	}
+}
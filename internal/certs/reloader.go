@@ -0,0 +1,190 @@
+// Package certs serves TLS certificates that are reloaded from disk when the
+// files change, so certificates can be rotated without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client certificate policies for mutual TLS.
+const (
+	ClientAuthNone    = "none"
+	ClientAuthRequest = "request"
+	ClientAuthRequire = "require"
+)
+
+// Reloader holds the current server certificate and client CA pool.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+	logger       *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the certificate, key and optional client CA bundle.
+// clientAuth is one of the ClientAuth constants and requires a client CA
+// bundle unless it is ClientAuthNone.
+func NewReloader(certFile, keyFile, clientCAFile, clientAuth string, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+
+	switch clientAuth {
+	case "", ClientAuthNone:
+		r.clientAuth = tls.NoClientCert
+	case ClientAuthRequest:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown TLS client auth mode %q", clientAuth)
+	}
+	if r.clientAuth != tls.NoClientCert && clientCAFile == "" {
+		return nil, errors.New("mutual TLS requires a client CA file")
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ServerConfig returns a TLS configuration for the gRPC port that always uses
+// the most recently loaded certificate and client CAs.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return r.serverConfig("h2")
+}
+
+// HTTPServerConfig is like ServerConfig, but also accepts HTTP/1.1 for the
+// gateway and gRPC-Web clients.
+func (r *Reloader) HTTPServerConfig() *tls.Config {
+	return r.serverConfig("h2", "http/1.1")
+}
+
+func (r *Reloader) serverConfig(protocols ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: protocols,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.clientCA,
+				NextProtos:   protocols,
+			}, nil
+		},
+	}
+}
+
+// Run checks the files every interval and reloads them after a change. A
+// failed reload keeps serving the previous certificate.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Error("Failed to reload TLS certificates", "error", err)
+				continue
+			}
+			r.logger.Info("Reloaded TLS certificates", "cert_file", r.certFile)
+		}
+	}
+}
+
+func (r *Reloader) load() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCA *x509.CertPool
+	if r.clientCAFile != "" {
+		caPEM, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.clientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCA = clientCA
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	return nil
+}
+
+// changed reports whether any file was modified since the last load.
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		// Files are briefly missing while they are being replaced
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for path, t := range modTimes {
+		if !t.Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stat follows symlinks, so Kubernetes secret updates that swap a symlinked
+// directory are detected.
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := map[string]time.Time{}
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
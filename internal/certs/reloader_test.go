@@ -0,0 +1,170 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// writeCertificate writes a self-signed certificate for commonName and its
+// key to dir and moves their modification time to modTime.
+func writeCertificate(t *testing.T, dir, commonName string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// served returns the common name and client auth mode of the configuration
+// the reloader currently serves.
+func served(t *testing.T, r *Reloader) (string, tls.ClientAuthType) {
+	t.Helper()
+	config, err := r.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName, config.ClientAuth
+}
+
+func TestNewReloaderClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "user-profile", time.Now())
+
+	tests := []struct {
+		clientAuth string
+		clientCA   string
+		want       tls.ClientAuthType
+		wantErr    bool
+	}{
+		{clientAuth: "", want: tls.NoClientCert},
+		{clientAuth: ClientAuthNone, want: tls.NoClientCert},
+		{clientAuth: ClientAuthRequest, clientCA: certFile, want: tls.VerifyClientCertIfGiven},
+		{clientAuth: ClientAuthRequire, clientCA: certFile, want: tls.RequireAndVerifyClientCert},
+		{clientAuth: ClientAuthRequire, wantErr: true},
+		{clientAuth: "optional", clientCA: certFile, wantErr: true},
+		{clientAuth: ClientAuthRequire, clientCA: keyFile, wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := NewReloader(certFile, keyFile, tt.clientCA, tt.clientAuth, testLogger)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewReloader(%q, client CA %q) succeeded, want an error", tt.clientAuth, tt.clientCA)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewReloader(%q): %v", tt.clientAuth, err)
+			continue
+		}
+		if _, got := served(t, r); got != tt.want {
+			t.Errorf("client auth for %q = %v, want %v", tt.clientAuth, got, tt.want)
+		}
+	}
+}
+
+func TestReloaderPicksUpChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	loaded := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeCertificate(t, dir, "old", loaded)
+
+	r, err := NewReloader(certFile, keyFile, "", ClientAuthNone, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := served(t, r); name != "old" {
+		t.Fatalf("served %q, want old", name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, 10*time.Millisecond)
+
+	writeCertificate(t, dir, "new", time.Now())
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		name, _ := served(t, r)
+		if name == "new" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("served %q after the files changed, want new", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloaderKeepsCertificateWhenReloadFails(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "current", time.Now().Add(-time.Minute))
+
+	r, err := NewReloader(certFile, keyFile, "", ClientAuthNone, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A certificate whose key has not been written yet
+	writeFile(t, certFile, []byte("not a certificate"), time.Now())
+	if !r.changed() {
+		t.Fatal("changed = false after the certificate file was written")
+	}
+	if err := r.load(); err == nil {
+		t.Fatal("load succeeded with an invalid certificate")
+	}
+	if name, _ := served(t, r); name != "current" {
+		t.Errorf("served %q after a failed reload, want current", name)
+	}
+	if !r.changed() {
+		t.Error("changed = false after a failed reload, want the reload retried")
+	}
+}
@@ -0,0 +1,79 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const minIndexKeySize = 32
+
+// BlindIndex computes keyed hashes of normalized values, so that encrypted
+// columns can be searched by equality without storing the plaintext. Unlike
+// the encryption keys, the index key cannot be rotated without recomputing
+// every index.
+type BlindIndex struct {
+	key []byte
+}
+
+func NewBlindIndex(key []byte) (*BlindIndex, error) {
+	if len(key) < minIndexKeySize {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes, got %d", minIndexKeySize, len(key))
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// Compute returns the index of an already normalized value. domain separates
+// the indexes of different columns, so equal values in two columns do not
+// produce equal indexes. Empty values have an empty index.
+func (b *BlindIndex) Compute(domain, normalized string) string {
+	if normalized == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NormalizePhone reduces a phone number to its digits, keeping a leading
+// "+" and turning a leading "00" into "+", so that "+49 (30) 123-45" and
+// "0049 30 12345" match.
+func NormalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+	if !international && strings.HasPrefix(phone, "00") {
+		international = true
+		phone = phone[2:]
+	}
+
+	var b strings.Builder
+	if international {
+		b.WriteByte('+')
+	}
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 || b.String() == "+" {
+		return ""
+	}
+	return b.String()
+}
+
+// NormalizeLicense upper-cases a driving license number and drops
+// separators such as spaces and dashes.
+func NormalizeLicense(license string) string {
+	var b strings.Builder
+	for _, r := range license {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package encryption
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"+48 123 456 789", "+48123456789"},
+		{"+49 (30) 123-45", "+493012345"},
+		{"0049 30 12345", "+493012345"},
+		{"  +48123456789  ", "+48123456789"},
+		{"030 12345", "03012345"},
+		{"123-456-789", "123456789"},
+		{"+", ""},
+		{"00", ""},
+		{"phone", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizePhone(tt.phone); got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := []struct {
+		license string
+		want    string
+	}{
+		{"PL-12345678", "PL12345678"},
+		{"pl 1234 5678", "PL12345678"},
+		{"B072RRE2I55", "B072RRE2I55"},
+		{"müller-123", "MÜLLER123"},
+		{" - ", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeLicense(tt.license); got != tt.want {
+			t.Errorf("NormalizeLicense(%q) = %q, want %q", tt.license, got, tt.want)
+		}
+	}
+}
+
+func TestBlindIndexSeparatesDomains(t *testing.T) {
+	index, err := NewBlindIndex(testKey(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone := index.Compute("phone_index", "+48123456789")
+	if phone != index.Compute("phone_index", NormalizePhone("0048 123 456 789")) {
+		t.Error("equal normalized phone numbers have different indexes")
+	}
+	if phone == index.Compute("driving_license_index", "+48123456789") {
+		t.Error("the same value has the same index in two columns")
+	}
+}
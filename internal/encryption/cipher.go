@@ -0,0 +1,274 @@
+// Package encryption implements envelope encryption of personal data at rest.
+//
+// Every value is encrypted with its own random data key, and the data key is
+// wrapped with the primary key-encryption key of the local keyring. Both use
+// XChaCha20-Poly1305, whose 192-bit nonces are safe to choose at random.
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Envelope layout:
+//
+//	byte 0:            envelope version
+//	key ID:            1 length byte + ID (version 2 only)
+//	wrap nonce:        24 bytes
+//	wrapped data key:  32 bytes + 16 byte tag
+//	data nonce:        24 bytes
+//	ciphertext:        len(plaintext) + 16 byte tag
+//
+// The version and key ID are authenticated with the wrapped data key.
+// Version 1 envelopes were written before key rotation and carry no key ID.
+const (
+	envelopeV1 byte = 1
+	envelopeV2 byte = 2
+
+	wrappedKeySize = chacha20poly1305.KeySize + chacha20poly1305.Overhead
+	keySectionSize = chacha20poly1305.NonceSizeX + wrappedKeySize + chacha20poly1305.NonceSizeX
+	maxKeyIDLength = 255
+)
+
+var ErrDecrypt = errors.New("failed to decrypt value")
+
+// FieldCipher encrypts values with the primary key of a keyring and decrypts
+// values written with any of its keys.
+type FieldCipher struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewFieldCipher builds a cipher from 32-byte keys by key ID. New values are
+// encrypted with the primary key.
+func NewFieldCipher(primary string, keys map[string][]byte) (*FieldCipher, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+
+	c := &FieldCipher{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > maxKeyIDLength {
+			return nil, fmt.Errorf("key ID %q must be 1 to %d bytes", id, maxKeyIDLength)
+		}
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, chacha20poly1305.KeySize, len(key))
+		}
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[id] = aead
+	}
+
+	return c, nil
+}
+
+// PrimaryKeyID returns the ID of the key new values are encrypted with.
+func (c *FieldCipher) PrimaryKeyID() string {
+	return c.primary
+}
+
+// Encrypt seals plaintext in a new envelope. aad binds the ciphertext to its
+// context, such as the column and row it is stored in, and must be passed
+// unchanged to Decrypt.
+func (c *FieldCipher) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	dataKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataNonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(dataNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrap(dataKey, dataNonce, aead.Seal(nil, dataNonce, plaintext, aad))
+}
+
+func (c *FieldCipher) Decrypt(data, aad []byte) ([]byte, error) {
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := c.unwrap(e)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, e.dataNonce, e.ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: authentication failed", ErrDecrypt)
+	}
+	return plaintext, nil
+}
+
+// Rewrap wraps the data key of an envelope with the primary key, leaving the
+// ciphertext unchanged. It reports false when the envelope already uses the
+// primary key.
+func (c *FieldCipher) Rewrap(data []byte) ([]byte, bool, error) {
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.version == envelopeV2 && e.keyID == c.primary {
+		return data, false, nil
+	}
+
+	dataKey, err := c.unwrap(e)
+	if err != nil {
+		return nil, false, err
+	}
+	rewrapped, err := c.wrap(dataKey, e.dataNonce, e.ciphertext)
+	if err != nil {
+		return nil, false, err
+	}
+	return rewrapped, true, nil
+}
+
+// EncryptString encrypts a text value for storage as base64. Empty values stay
+// empty so that optional columns remain distinguishable from set ones. The
+// encoding does not mark the value as encrypted, so callers that also store
+// plaintext must record which values are encrypted.
+func (c *FieldCipher) EncryptString(value, aad string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	envelope, err := c.Encrypt([]byte(value), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(envelope), nil
+}
+
+// DecryptString reverses EncryptString.
+func (c *FieldCipher) DecryptString(value, aad string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	envelope, err := decodeString(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := c.Decrypt(envelope, []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// RewrapString is Rewrap for values produced by EncryptString.
+func (c *FieldCipher) RewrapString(value string) (string, bool, error) {
+	if value == "" {
+		return value, false, nil
+	}
+	envelope, err := decodeString(value)
+	if err != nil {
+		return value, false, err
+	}
+	rewrapped, changed, err := c.Rewrap(envelope)
+	if err != nil || !changed {
+		return value, false, err
+	}
+	return base64.RawStdEncoding.EncodeToString(rewrapped), true, nil
+}
+
+func decodeString(value string) ([]byte, error) {
+	envelope, err := base64.RawStdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	return envelope, nil
+}
+
+type envelope struct {
+	version byte
+	keyID   string
+	// header is the version and key ID, authenticated with the wrapped key.
+	header     []byte
+	wrapNonce  []byte
+	wrappedKey []byte
+	dataNonce  []byte
+	ciphertext []byte
+}
+
+func parseEnvelope(data []byte) (*envelope, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+	}
+
+	e := &envelope{version: data[0]}
+	switch e.version {
+	case envelopeV1:
+		e.header = data[:1]
+	case envelopeV2:
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+		}
+		e.header = data[:2+int(data[1])]
+		e.keyID = string(data[2:len(e.header)])
+	default:
+		return nil, fmt.Errorf("%w: unknown envelope version %d", ErrDecrypt, e.version)
+	}
+
+	rest := data[len(e.header):]
+	if len(rest) < keySectionSize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("%w: malformed envelope", ErrDecrypt)
+	}
+	e.wrapNonce = rest[:chacha20poly1305.NonceSizeX]
+	e.wrappedKey = rest[chacha20poly1305.NonceSizeX : chacha20poly1305.NonceSizeX+wrappedKeySize]
+	e.dataNonce = rest[chacha20poly1305.NonceSizeX+wrappedKeySize : keySectionSize]
+	e.ciphertext = rest[keySectionSize:]
+
+	return e, nil
+}
+
+// wrap builds a version 2 envelope for the primary key.
+func (c *FieldCipher) wrap(dataKey, dataNonce, ciphertext []byte) ([]byte, error) {
+	header := append([]byte{envelopeV2, byte(len(c.primary))}, c.primary...)
+	wrapNonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(wrapNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+keySectionSize+len(ciphertext))
+	out = append(out, header...)
+	out = append(out, wrapNonce...)
+	out = c.keys[c.primary].Seal(out, wrapNonce, dataKey, header)
+	out = append(out, dataNonce...)
+	return append(out, ciphertext...), nil
+}
+
+func (c *FieldCipher) unwrap(e *envelope) ([]byte, error) {
+	if e.version == envelopeV1 {
+		// Version 1 envelopes name no key, so every key is tried
+		for _, kek := range c.keys {
+			if dataKey, err := kek.Open(nil, e.wrapNonce, e.wrappedKey, e.header); err == nil {
+				return dataKey, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no key in the keyring unwraps the data key", ErrDecrypt)
+	}
+
+	kek, ok := c.keys[e.keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: key %q is not in the keyring", ErrDecrypt, e.keyID)
+	}
+	dataKey, err := kek.Open(nil, e.wrapNonce, e.wrappedKey, e.header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot unwrap data key", ErrDecrypt)
+	}
+	return dataKey, nil
+}
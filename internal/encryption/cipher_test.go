@@ -0,0 +1,237 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func newTestCipher(t *testing.T, primary string, keys map[string][]byte) *FieldCipher {
+	t.Helper()
+	c, err := NewFieldCipher(primary, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c := newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})
+	aad := []byte("user_profiles.phone:user-1")
+
+	envelope, err := c.Encrypt([]byte("+48123456789"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(envelope, []byte("+48123456789")) {
+		t.Error("envelope contains the plaintext")
+	}
+	plaintext, err := c.Decrypt(envelope, aad)
+	if err != nil || string(plaintext) != "+48123456789" {
+		t.Errorf("Decrypt = %q, %v, want +48123456789", plaintext, err)
+	}
+
+	other, err := c.Encrypt([]byte("+48123456789"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(envelope, other) {
+		t.Error("encrypting the same value twice gave the same envelope")
+	}
+}
+
+func TestCipherRejects(t *testing.T) {
+	c := newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})
+	aad := []byte("user_profiles.phone:user-1")
+	envelope, err := c.Encrypt([]byte("+48123456789"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Clone(envelope)
+	tampered[len(tampered)-1] ^= 1
+	wrongKey := newTestCipher(t, "k1", map[string][]byte{"k1": testKey(2)})
+
+	tests := []struct {
+		name   string
+		cipher *FieldCipher
+		data   []byte
+		aad    []byte
+	}{
+		{name: "wrong key", cipher: wrongKey, data: envelope, aad: aad},
+		{name: "wrong AAD", cipher: c, data: envelope, aad: []byte("user_profiles.phone:user-2")},
+		{name: "tampered ciphertext", cipher: c, data: tampered, aad: aad},
+		{name: "truncated", cipher: c, data: envelope[:20], aad: aad},
+		{name: "empty", cipher: c, aad: aad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cipher.Decrypt(tt.data, tt.aad); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Decrypt = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestCipherStrings(t *testing.T) {
+	c := newTestCipher(t, "k1", map[string][]byte{"k1": testKey(1)})
+
+	encrypted, err := c.EncryptString("ul. Marszalkowska 10/12", "user_profiles.address:user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := c.DecryptString(encrypted, "user_profiles.address:user-1")
+	if err != nil || decrypted != "ul. Marszalkowska 10/12" {
+		t.Errorf("DecryptString = %q, %v, want the address", decrypted, err)
+	}
+
+	// Empty values pass through so optional columns stay empty
+	if got, err := c.EncryptString("", "aad"); got != "" || err != nil {
+		t.Errorf("EncryptString(\"\") = %q, %v, want empty", got, err)
+	}
+	if got, err := c.DecryptString("", "aad"); got != "" || err != nil {
+		t.Errorf("DecryptString(\"\") = %q, %v, want empty", got, err)
+	}
+	if _, err := c.DecryptString("not base64!", "aad"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("DecryptString of plaintext = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestNewFieldCipherValidatesKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary string
+		keys    map[string][]byte
+	}{
+		{name: "missing primary", primary: "k2", keys: map[string][]byte{"k1": testKey(1)}},
+		{name: "short key", primary: "k1", keys: map[string][]byte{"k1": testKey(1)[:16]}},
+		{name: "empty key ID", primary: "", keys: map[string][]byte{"": testKey(1)}},
+	}
+	for _, tt := range tests {
+		if _, err := NewFieldCipher(tt.primary, tt.keys); err == nil {
+			t.Errorf("%s: NewFieldCipher succeeded, want an error", tt.name)
+		}
+	}
+}
+
+// sealV1 builds a version 1 envelope, as written before key rotation, with
+// the key-encryption key kek.
+func sealV1(t *testing.T, kek, plaintext, aad []byte) []byte {
+	t.Helper()
+	dataKey, dataNonce, wrapNonce := testKey(7), bytes.Repeat([]byte{8}, 24), bytes.Repeat([]byte{9}, 24)
+	header := []byte{envelopeV1}
+
+	wrapper, err := chacha20poly1305.NewX(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := chacha20poly1305.NewX(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := append(bytes.Clone(header), wrapNonce...)
+	out = wrapper.Seal(out, wrapNonce, dataKey, header)
+	out = append(out, dataNonce...)
+	return data.Seal(out, dataNonce, plaintext, aad)
+}
+
+func TestParseEnvelope(t *testing.T) {
+	c := newTestCipher(t, "2026-q4", map[string][]byte{"2026-q4": testKey(1)})
+	v2, err := c.Encrypt([]byte("PL-12345678"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := parseEnvelope(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.version != envelopeV2 || e.keyID != "2026-q4" || !bytes.Equal(e.header, v2[:2+len("2026-q4")]) {
+		t.Errorf("v2 envelope = version %d, key %q, header %x", e.version, e.keyID, e.header)
+	}
+
+	e, err = parseEnvelope(sealV1(t, testKey(1), []byte("PL-12345678"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.version != envelopeV1 || e.keyID != "" || len(e.header) != 1 {
+		t.Errorf("v1 envelope = version %d, key %q, header %x", e.version, e.keyID, e.header)
+	}
+
+	unknown := bytes.Clone(v2)
+	unknown[0] = 3
+	keyIDTooLong := []byte{envelopeV2, 200, 'k'}
+	for name, data := range map[string][]byte{"unknown version": unknown, "key ID past the end": keyIDTooLong, "truncated": v2[:40]} {
+		if _, err := parseEnvelope(data); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: parseEnvelope = %v, want ErrDecrypt", name, err)
+		}
+	}
+}
+
+func TestDecryptSelectsKeyByID(t *testing.T) {
+	old := newTestCipher(t, "2026-q3", map[string][]byte{"2026-q3": testKey(1)})
+	envelope, err := old.Encrypt([]byte("PL-12345678"), []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestCipher(t, "2026-q4", map[string][]byte{"2026-q3": testKey(1), "2026-q4": testKey(2)})
+	if plaintext, err := rotated.Decrypt(envelope, []byte("aad")); err != nil || string(plaintext) != "PL-12345678" {
+		t.Errorf("Decrypt with the old key in the keyring = %q, %v", plaintext, err)
+	}
+
+	retired := newTestCipher(t, "2026-q4", map[string][]byte{"2026-q4": testKey(2)})
+	if _, err := retired.Decrypt(envelope, []byte("aad")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt after the old key was removed = %v, want ErrDecrypt", err)
+	}
+
+	// Version 1 envelopes name no key, so every key is tried
+	v1 := sealV1(t, testKey(1), []byte("PL-12345678"), []byte("aad"))
+	if plaintext, err := rotated.Decrypt(v1, []byte("aad")); err != nil || string(plaintext) != "PL-12345678" {
+		t.Errorf("Decrypt of a v1 envelope = %q, %v", plaintext, err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	old := newTestCipher(t, "2026-q3", map[string][]byte{"2026-q3": testKey(1)})
+	rotated := newTestCipher(t, "2026-q4", map[string][]byte{"2026-q3": testKey(1), "2026-q4": testKey(2)})
+	retired := newTestCipher(t, "2026-q4", map[string][]byte{"2026-q4": testKey(2)})
+
+	v2, err := old.Encrypt([]byte("PL-12345678"), []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, envelope := range map[string][]byte{"v1": sealV1(t, testKey(1), []byte("PL-12345678"), []byte("aad")), "v2": v2} {
+		t.Run(name, func(t *testing.T) {
+			rewrapped, changed, err := rotated.Rewrap(envelope)
+			if err != nil || !changed {
+				t.Fatalf("Rewrap = %v, %t, want a rewrapped envelope", err, changed)
+			}
+
+			before, _ := parseEnvelope(envelope)
+			after, err := parseEnvelope(rewrapped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.version != envelopeV2 || after.keyID != "2026-q4" {
+				t.Errorf("rewrapped envelope = version %d, key %q, want v2 with 2026-q4", after.version, after.keyID)
+			}
+			if !bytes.Equal(after.ciphertext, before.ciphertext) || !bytes.Equal(after.dataNonce, before.dataNonce) {
+				t.Error("Rewrap changed the ciphertext")
+			}
+			if plaintext, err := retired.Decrypt(rewrapped, []byte("aad")); err != nil || string(plaintext) != "PL-12345678" {
+				t.Errorf("Decrypt with only the new key = %q, %v", plaintext, err)
+			}
+
+			again, changed, err := rotated.Rewrap(rewrapped)
+			if err != nil || changed || !bytes.Equal(again, rewrapped) {
+				t.Errorf("Rewrap of an envelope with the primary key = %t, %v, want it unchanged", changed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LegacyKeyID is the ID of the key in single-key keyring files.
+const LegacyKeyID = "default"
+
+// keyringFile is the JSON layout of the local keyring file. Keys are random
+// bytes in standard base64, by key ID:
+//
+//	{"primary": "2026-q4", "keys": {"2026-q3": "...", "2026-q4": "..."}, "index_key": "..."}
+//
+// The single-key layout {"key": "..."} is still accepted.
+type keyringFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+	Key     string            `json:"key"`
+	// IndexKey is the HMAC key of the blind indexes.
+	IndexKey string `json:"index_key"`
+}
+
+func readKeyring(path string) (*keyringFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+	return &file, nil
+}
+
+// LoadKeyring reads the encryption keys of a keyring file.
+func LoadKeyring(path string) (*FieldCipher, error) {
+	file, err := readKeyring(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Key != "" {
+		if len(file.Keys) > 0 {
+			return nil, errors.New("keyring must not set both key and keys")
+		}
+		file.Primary = LegacyKeyID
+		file.Keys = map[string]string{LegacyKeyID: file.Key}
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode keyring key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewFieldCipher(file.Primary, keys)
+}
+
+// LoadBlindIndex reads the blind index key of a keyring file. It returns nil
+// when the keyring has no index key.
+func LoadBlindIndex(path string) (*BlindIndex, error) {
+	file, err := readKeyring(path)
+	if err != nil || file.IndexKey == "" {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode blind index key: %w", err)
+	}
+	return NewBlindIndex(key)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"github.com/Brrocat/user-profile-service/proto/accesslog"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+)
+
+// AccessLogHandler serves AccessLogService.
+type AccessLogHandler struct {
+	accesslog.UnimplementedAccessLogServiceServer
+	accessLog service.AccessLog
+	logger    *slog.Logger
+}
+
+func NewAccessLogHandler(accessLog service.AccessLog, logger *slog.Logger) *AccessLogHandler {
+	return &AccessLogHandler{
+		accessLog: accessLog,
+		logger:    logger,
+	}
+}
+
+func (h *AccessLogHandler) QueryAccessLog(ctx context.Context, req *accesslog.QueryAccessLogRequest) (*accesslog.QueryAccessLogResponse, error) {
+	filter := models.AccessLogFilter{
+		UserID:     req.UserId,
+		Caller:     req.Caller,
+		BeforeID:   req.BeforeId,
+		Limit:      int(req.Limit),
+		BreakGlass: req.BreakGlass,
+	}
+	if req.Since != nil {
+		filter.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		filter.Until = req.Until.AsTime()
+	}
+
+	page, err := h.accessLog.QueryAccessLog(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Warn("QueryAccessLog failed", "error", err)
+		return nil, statusFromError(err).Err()
+	}
+
+	resp := &accesslog.QueryAccessLogResponse{
+		Records:      make([]*accesslog.AccessRecord, 0, len(page.Records)),
+		NextBeforeId: page.NextBeforeID,
+	}
+	for _, rec := range page.Records {
+		resp.Records = append(resp.Records, &accesslog.AccessRecord{
+			Id:                rec.ID,
+			AccessedAt:        timestamppb.New(rec.AccessedAt),
+			Caller:            rec.Caller,
+			Roles:             rec.Roles,
+			Method:            rec.Method,
+			UserId:            rec.UserID,
+			Purpose:           rec.Purpose,
+			Fields:            rec.Fields,
+			RequestId:         rec.RequestID,
+			BreakGlassGrantId: rec.BreakGlassGrantID,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxLogLevelTTL bounds how long a runtime log level override can last.
+const maxLogLevelTTL = 24 * time.Hour
+
+// AdminHandler serves operational endpoints on the admin port. Every request
+// must carry the configured token as a bearer token.
+type AdminHandler struct {
+	levels     *logging.LevelController
+	token      string
+	defaultTTL time.Duration
+	mux        *http.ServeMux
+}
+
+type logLevelRequest struct {
+	Level  string `json:"level"`
+	TTL    string `json:"ttl"`
+	Reason string `json:"reason"`
+}
+
+type logLevelResponse struct {
+	Level     string     `json:"level"`
+	Default   string     `json:"default"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func NewAdminHandler(levels *logging.LevelController, token string, defaultTTL time.Duration) *AdminHandler {
+	h := &AdminHandler{
+		levels:     levels,
+		token:      token,
+		defaultTTL: defaultTTL,
+		mux:        http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /admin/log-level", h.getLogLevel)
+	h.mux.HandleFunc("PUT /admin/log-level", h.setLogLevel)
+	h.mux.HandleFunc("DELETE /admin/log-level", h.resetLogLevel)
+
+	return h
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		writeError(w, status.New(codes.Unauthenticated, "missing or invalid admin token"))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *AdminHandler) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, newLogLevelResponse(h.levels.State()))
+}
+
+func (h *AdminHandler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, status.Newf(codes.InvalidArgument, "invalid request body: %v", err))
+		return
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		writeError(w, status.Newf(codes.InvalidArgument, "invalid level %q", req.Level))
+		return
+	}
+
+	ttl := h.defaultTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			writeError(w, status.Newf(codes.InvalidArgument, "invalid ttl %q", req.TTL))
+			return
+		}
+	}
+	if ttl <= 0 || ttl > maxLogLevelTTL {
+		writeError(w, status.Newf(codes.InvalidArgument, "ttl must be between 0 and %s", maxLogLevelTTL))
+		return
+	}
+
+	h.levels.Set(level, ttl, adminActor(r), req.Reason)
+	writeJSON(w, http.StatusOK, newLogLevelResponse(h.levels.State()))
+}
+
+func (h *AdminHandler) resetLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.levels.Reset(adminActor(r))
+	writeJSON(w, http.StatusOK, newLogLevelResponse(h.levels.State()))
+}
+
+func newLogLevelResponse(state logging.LevelState) logLevelResponse {
+	resp := logLevelResponse{
+		Level:   state.Level.String(),
+		Default: state.Default.String(),
+	}
+	if !state.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &state.ExpiresAt
+	}
+	return resp
+}
+
+// adminActor identifies the caller in audit records. The admin token is
+// shared, so the remote address and user agent are all we have.
+func adminActor(r *http.Request) string {
+	return fmt.Sprintf("%s (%s)", r.RemoteAddr, r.UserAgent())
+}
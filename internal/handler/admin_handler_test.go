@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveAdmin(h http.Handler, method, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	var level slog.LevelVar
+	h := NewAdminHandler(logging.NewLevelController(&level, slog.New(slog.DiscardHandler)), "secret", time.Minute)
+
+	for _, token := range []string{"", "wrong"} {
+		if rec := serveAdmin(h, http.MethodGet, token, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET with token %q status = %d, want 401", token, rec.Code)
+		}
+	}
+
+	rec := serveAdmin(h, http.MethodPut, "secret", `{"level":"debug","reason":"incident 42"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", rec.Code, rec.Body)
+	}
+	resp := decodeResponse[logLevelResponse](t, rec)
+	if resp.Level != "DEBUG" || resp.Default != "INFO" || resp.ExpiresAt == nil {
+		t.Errorf("PUT response = %+v, want DEBUG over INFO with an expiry", resp)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level = %s, want DEBUG", level.Level())
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"level":"debug","ttl":"48h"}`, `{"level":"debug","ttl":"-1m"}`, `{"level":"debug","extra":1}`} {
+		if rec := serveAdmin(h, http.MethodPut, "secret", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s status = %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec = serveAdmin(h, http.MethodDelete, "secret", "")
+	if resp := decodeResponse[logLevelResponse](t, rec); rec.Code != http.StatusOK || resp.Level != "INFO" || resp.ExpiresAt != nil {
+		t.Errorf("DELETE = %d %+v, want INFO without expiry", rec.Code, resp)
+	}
+}
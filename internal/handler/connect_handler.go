@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/Brrocat/car-sharing-protos/proto/userprofile"
+	"github.com/Brrocat/user-profile-service/proto/accesslog"
+	"github.com/Brrocat/user-profile-service/proto/profilelookup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+)
+
+// NewConnectHandler serves UserProfileService, ProfileLookupService and
+// AccessLogService over the Connect, gRPC-Web and gRPC protocols on the HTTP
+// listener, so browsers can call them without a proxy. Calls go through the
+// given gRPC interceptors and then the handlers, exactly like calls arriving
+// on the gRPC port, including the client certificate of TLS connections. It
+// returns the path prefix of each service.
+func NewConnectHandler(profileHandler *ProfileHandler, lookupHandler *LookupHandler, accessLogHandler *AccessLogHandler, interceptors ...grpc.UnaryServerInterceptor) ([]string, http.Handler) {
+	intercept := chainUnary(interceptors)
+	mux := http.NewServeMux()
+
+	mux.Handle(unaryProcedure(userprofile.UserProfileService_GetUserProfile_FullMethodName, profileHandler, intercept, profileHandler.GetUserProfile))
+	mux.Handle(unaryProcedure(userprofile.UserProfileService_CreateUserProfile_FullMethodName, profileHandler, intercept, profileHandler.CreateUserProfile))
+	mux.Handle(unaryProcedure(userprofile.UserProfileService_UpdateUserProfile_FullMethodName, profileHandler, intercept, profileHandler.UpdateUserProfile))
+	mux.Handle(unaryProcedure(userprofile.UserProfileService_DeleteUserProfile_FullMethodName, profileHandler, intercept, profileHandler.DeleteUserProfile))
+	mux.Handle(unaryProcedure(profilelookup.ProfileLookupService_FindProfileByPhone_FullMethodName, lookupHandler, intercept, lookupHandler.FindProfileByPhone))
+	mux.Handle(unaryProcedure(profilelookup.ProfileLookupService_FindProfileByLicense_FullMethodName, lookupHandler, intercept, lookupHandler.FindProfileByLicense))
+	mux.Handle(unaryProcedure(accesslog.AccessLogService_QueryAccessLog_FullMethodName, accessLogHandler, intercept, accessLogHandler.QueryAccessLog))
+
+	paths := []string{
+		"/" + userprofile.UserProfileService_ServiceDesc.ServiceName + "/",
+		"/" + profilelookup.ProfileLookupService_ServiceDesc.ServiceName + "/",
+		"/" + accesslog.AccessLogService_ServiceDesc.ServiceName + "/",
+	}
+	return paths, withTLSState(mux)
+}
+
+type tlsStateKey struct{}
+
+// withTLSState passes the TLS connection state to unaryProcedure, which
+// cannot see the HTTP request.
+func withTLSState(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			r = r.WithContext(context.WithValue(r.Context(), tlsStateKey{}, r.TLS))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func unaryProcedure[Req, Res any](
+	procedure string,
+	server any,
+	intercept grpc.UnaryServerInterceptor,
+	call func(context.Context, *Req) (*Res, error),
+) (string, http.Handler) {
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: procedure}
+
+	return procedure, connect.NewUnaryHandler(procedure, func(ctx context.Context, req *connect.Request[Req]) (*connect.Response[Res], error) {
+		md := metadata.MD{}
+		for key, values := range req.Header() {
+			md.Append(key, values...)
+		}
+		ctx = metadata.NewIncomingContext(ctx, md)
+
+		p := &peer.Peer{}
+		if addr, err := net.ResolveTCPAddr("tcp", req.Peer().Addr); err == nil {
+			p.Addr = addr
+		}
+		if state, ok := ctx.Value(tlsStateKey{}).(*tls.ConnectionState); ok {
+			p.AuthInfo = credentials.TLSInfo{State: *state}
+		}
+		ctx = peer.NewContext(ctx, p)
+
+		// Lets interceptors and handlers use grpc.SetHeader as usual
+		stream := &connectTransportStream{method: procedure}
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+		resp, err := intercept(ctx, req.Msg, info, func(ctx context.Context, msg any) (any, error) {
+			return call(ctx, msg.(*Req))
+		})
+		if err != nil {
+			st := status.Convert(err)
+			connectErr := connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+			copyMetadata(connectErr.Meta(), stream.header, stream.trailer)
+			return nil, connectErr
+		}
+
+		res := connect.NewResponse(resp.(*Res))
+		copyMetadata(res.Header(), stream.header)
+		copyMetadata(res.Trailer(), stream.trailer)
+		return res, nil
+	})
+}
+
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+func copyMetadata(dst http.Header, mds ...metadata.MD) {
+	for _, md := range mds {
+		for key, values := range md {
+			for _, value := range values {
+				dst.Add(key, value)
+			}
+		}
+	}
+}
+
+// connectTransportStream collects headers and trailers set through the grpc
+// package so they can be returned on the Connect response.
+type connectTransportStream struct {
+	method  string
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *connectTransportStream) Method() string {
+	return s.method
+}
+
+func (s *connectTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *connectTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *connectTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
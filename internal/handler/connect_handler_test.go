@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/Brrocat/car-sharing-protos/proto/userprofile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConnectPassesClientCertificate(t *testing.T) {
+	var identity string
+	capture := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				identity = tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "stop")
+	}
+	_, h := NewConnectHandler(nil, nil, nil, capture)
+
+	req := httptest.NewRequest(http.MethodPost, userprofile.UserProfileService_GetUserProfile_FullMethodName, strings.NewReader(`{"user_id":"`+testUserID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "booking-service"}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401 from the interceptor: %s", rec.Code, rec.Body)
+	}
+	if identity != "booking-service" {
+		t.Errorf("interceptor saw client certificate %q, want booking-service", identity)
+	}
+}
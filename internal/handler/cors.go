@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"github.com/rs/cors"
+	"net/http"
+)
+
+// WithCORS allows browsers on the given origins to call h with the headers
+// used by the Connect and gRPC-Web protocols. An origin of "*" allows any
+// origin; no origins leaves h same-origin only.
+func WithCORS(h http.Handler, allowedOrigins []string) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return h
+	}
+
+	return cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{
+			"Authorization",
+			"Content-Type",
+			"Connect-Protocol-Version",
+			"Connect-Timeout-Ms",
+			"Grpc-Timeout",
+			"X-Grpc-Web",
+			"X-User-Agent",
+			requestIDHeader,
+			"X-Request-Priority",
+			"X-Access-Purpose",
+			"X-Break-Glass-Grant",
+			"Traceparent",
+			"Tracestate",
+		},
+		ExposedHeaders: []string{
+			"Grpc-Status",
+			"Grpc-Message",
+			"Grpc-Status-Details-Bin",
+			requestIDHeader,
+			"Retry-After",
+		},
+		MaxAge: 7200,
+	}).Handler(h)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusFromError maps service errors to gRPC statuses. The HTTP gateway
+// derives its responses from the same statuses so both APIs agree.
+func statusFromError(err error) *status.Status {
+	switch {
+	case errors.Is(err, service.ErrProfileNotFound):
+		return status.New(codes.NotFound, "profile not found")
+	case errors.Is(err, service.ErrGrantNotFound):
+		return status.New(codes.NotFound, "break-glass grant not found")
+	case errors.Is(err, service.ErrProfileAlreadyExists):
+		return status.New(codes.AlreadyExists, "profile already exists")
+	case errors.Is(err, service.ErrInvalidData):
+		return status.New(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrPermissionDenied):
+		return status.New(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrLookupUnavailable):
+		return status.New(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrAmbiguousLookup):
+		return status.New(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrBreakGlassUnavailable):
+		return status.New(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrAuditUnavailable):
+		return status.New(codes.Unavailable, "access audit log unavailable, retry later")
+	default:
+		return status.New(codes.Internal, "internal server error")
+	}
+}
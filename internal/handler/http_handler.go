@@ -0,0 +1,691 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/audit"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/loadshed"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/Brrocat/user-profile-service/internal/ratelimit"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxRequestBodySize = 1 << 20
+	requestIDHeader    = "X-Request-Id"
+)
+
+// HTTPHandler exposes ProfileService and AccessLogService as a JSON REST API for clients that
+// cannot speak gRPC, along with the HTTP-only break-glass routes. With a verifier, profile routes require a bearer token,
+// like the gRPC auth interceptor, and with a rate limit policy or load
+// shedder they are limited like the gRPC interceptors.
+type HTTPHandler struct {
+	profileService service.Profiles
+	accessLog      service.AccessLog
+	breakGlass     service.BreakGlass
+	verifier       *auth.Verifier
+	rateLimits     *ratelimit.Policy
+	loadShedder    *loadshed.Limiter
+	logger         *slog.Logger
+	mux            *http.ServeMux
+}
+
+type profileResponse struct {
+	Profile *userProfile `json:"profile"`
+}
+
+// userProfile carries the same fields as the gRPC UserProfile message, so
+// the gateway does not expose more of a profile than the gRPC API.
+type userProfile struct {
+	UserID      string    `json:"user_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Phone       string    `json:"phone"`
+	DateOfBirth string    `json:"date_of_birth"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newProfileResponse(profile *models.UserProfile) profileResponse {
+	return profileResponse{
+		Profile: &userProfile{
+			UserID:      profile.UserID,
+			FirstName:   profile.FirstName,
+			LastName:    profile.LastName,
+			Phone:       profile.Phone,
+			DateOfBirth: profile.DateOfBirth,
+			AvatarURL:   profile.AvatarURL,
+			CreatedAt:   profile.CreatedAt,
+			UpdatedAt:   profile.UpdatedAt,
+		},
+	}
+}
+
+// The user ID of create and update requests is taken from the path, so the
+// bodies do not carry it.
+type createProfileBody struct {
+	FirstName   string `json:"first_name" validate:"required"`
+	LastName    string `json:"last_name" validate:"required"`
+	Phone       string `json:"phone"`
+	DateOfBirth string `json:"date_of_birth"`
+}
+
+// updateProfileBody uses pointers so that fields missing from the body keep
+// their stored value, while fields sent as "" are cleared.
+type updateProfileBody struct {
+	FirstName      *string `json:"first_name"`
+	LastName       *string `json:"last_name"`
+	Phone          *string `json:"phone"`
+	DateOfBirth    *string `json:"date_of_birth"`
+	AvatarURL      *string `json:"avatar_url"`
+	Address        *string `json:"address"`
+	City           *string `json:"city"`
+	Country        *string `json:"country"`
+	PostalCode     *string `json:"postal_code"`
+	DrivingLicense *string `json:"driving_license"`
+}
+
+// request returns the update of the fields present in the body.
+func (b *updateProfileBody) request(userID string) *models.UpdateProfileRequest {
+	req := &models.UpdateProfileRequest{UserID: userID, Fields: []string{}}
+	for _, field := range []struct {
+		name     string
+		dst, src *string
+	}{
+		{"first_name", &req.FirstName, b.FirstName},
+		{"last_name", &req.LastName, b.LastName},
+		{"phone", &req.Phone, b.Phone},
+		{"date_of_birth", &req.DateOfBirth, b.DateOfBirth},
+		{"avatar_url", &req.AvatarURL, b.AvatarURL},
+		{"address", &req.Address, b.Address},
+		{"city", &req.City, b.City},
+		{"country", &req.Country, b.Country},
+		{"postal_code", &req.PostalCode, b.PostalCode},
+		{"driving_license", &req.DrivingLicense, b.DrivingLicense},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+			req.Fields = append(req.Fields, field.name)
+		}
+	}
+	return req
+}
+
+// Lookup values are sent in the body rather than the URL, so that they do
+// not end up in access logs.
+type findByPhoneRequest struct {
+	Phone string `json:"phone"`
+}
+
+type findByLicenseRequest struct {
+	DrivingLicense string `json:"driving_license"`
+}
+
+type accessLogResponse struct {
+	Records []models.AccessRecord `json:"records"`
+	// NextBeforeID continues the listing as the before_id parameter. It is
+	// omitted on the last page.
+	NextBeforeID int64 `json:"next_before_id,omitempty"`
+}
+
+type breakGlassResponse struct {
+	Grant *models.BreakGlassGrant `json:"grant"`
+}
+
+type deleteResponse struct {
+	Success bool `json:"success"`
+}
+
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewHTTPHandler builds the handler. breakGlass is nil when break-glass
+// access is unavailable.
+func NewHTTPHandler(profileService service.Profiles, accessLog service.AccessLog, breakGlass service.BreakGlass, verifier *auth.Verifier, rateLimits *ratelimit.Policy, loadShedder *loadshed.Limiter, logger *slog.Logger) *HTTPHandler {
+	h := &HTTPHandler{
+		profileService: profileService,
+		accessLog:      accessLog,
+		breakGlass:     breakGlass,
+		verifier:       verifier,
+		rateLimits:     rateLimits,
+		loadShedder:    loadShedder,
+		logger:         logger,
+		mux:            http.NewServeMux(),
+	}
+
+	routes := h.routes()
+	for _, rt := range routes {
+		h.mux.HandleFunc(rt.method+" "+rt.path, h.authenticate(h.shed(rt.operationID, h.rateLimit(rt.operationID, rt.handler))))
+	}
+
+	spec, err := json.Marshal(buildOpenAPI(routes, verifier != nil))
+	if err != nil {
+		// The document is built from static Go types, this cannot fail at runtime
+		panic(fmt.Sprintf("failed to marshal OpenAPI document: %v", err))
+	}
+	h.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+
+	return h
+}
+
+// route describes one gateway endpoint. The same table drives the mux and
+// the OpenAPI document, so the two cannot diverge.
+type route struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	query       []string
+	request     any
+	response    any
+	status      int
+	handler     http.HandlerFunc
+}
+
+func (h *HTTPHandler) routes() []route {
+	return []route{
+		{
+			method:      http.MethodGet,
+			path:        "/v1/profiles/{user_id}",
+			operationID: "GetUserProfile",
+			summary:     "Get a user profile",
+			response:    profileResponse{},
+			status:      http.StatusOK,
+			handler:     h.getProfile,
+		},
+		{
+			method:      http.MethodPost,
+			path:        "/v1/profiles/{user_id}",
+			operationID: "CreateUserProfile",
+			summary:     "Create a user profile",
+			request:     createProfileBody{},
+			response:    profileResponse{},
+			status:      http.StatusCreated,
+			handler:     h.createProfile,
+		},
+		{
+			method:      http.MethodPatch,
+			path:        "/v1/profiles/{user_id}",
+			operationID: "UpdateUserProfile",
+			summary:     "Update a user profile",
+			request:     updateProfileBody{},
+			response:    profileResponse{},
+			status:      http.StatusOK,
+			handler:     h.updateProfile,
+		},
+		{
+			method:      http.MethodDelete,
+			path:        "/v1/profiles/{user_id}",
+			operationID: "DeleteUserProfile",
+			summary:     "Delete a user profile",
+			response:    deleteResponse{},
+			status:      http.StatusOK,
+			handler:     h.deleteProfile,
+		},
+		{
+			method:      http.MethodPost,
+			path:        "/v1/profiles:findByPhone",
+			operationID: "FindProfileByPhone",
+			summary:     "Find a user profile by phone number",
+			request:     findByPhoneRequest{},
+			response:    profileResponse{},
+			status:      http.StatusOK,
+			handler:     h.findByPhone,
+		},
+		{
+			method:      http.MethodPost,
+			path:        "/v1/profiles:findByLicense",
+			operationID: "FindProfileByLicense",
+			summary:     "Find a user profile by driving license number",
+			request:     findByLicenseRequest{},
+			response:    profileResponse{},
+			status:      http.StatusOK,
+			handler:     h.findByLicense,
+		},
+		{
+			method:      http.MethodGet,
+			path:        "/v1/access-log",
+			operationID: "QueryAccessLog",
+			summary:     "List reads of personal data, newest first",
+			query:       []string{"user_id", "caller", "since", "until", "break_glass", "before_id", "limit"},
+			response:    accessLogResponse{},
+			status:      http.StatusOK,
+			handler:     h.queryAccessLog,
+		},
+		{
+			method:      http.MethodPost,
+			path:        "/v1/break-glass",
+			operationID: "RequestBreakGlass",
+			summary:     "Request short-lived access to every field of a profile",
+			request:     models.BreakGlassRequest{},
+			response:    breakGlassResponse{},
+			status:      http.StatusCreated,
+			handler:     h.requestBreakGlass,
+		},
+		{
+			method:      http.MethodDelete,
+			path:        "/v1/break-glass/{grant_id}",
+			operationID: "RevokeBreakGlass",
+			summary:     "Revoke a break-glass grant before it expires",
+			response:    breakGlassResponse{},
+			status:      http.StatusOK,
+			handler:     h.revokeBreakGlass,
+		},
+	}
+}
+
+// ServeHTTP assigns or propagates X-Request-Id and attaches a request-scoped
+// logger before routing the request.
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(requestIDHeader)
+	if !logging.ValidRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	w.Header().Set(requestIDHeader, id)
+
+	ctx := logging.WithRequestID(r.Context(), id)
+	ctx = logging.WithLogger(ctx, h.logger.With("request_id", id, "method", r.Method, "path", r.URL.Path))
+	ctx = audit.WithPurpose(ctx, r.Header.Get(audit.PurposeHeader))
+	if grantID := r.Header.Get(breakglass.GrantHeader); grantID != "" {
+		ctx = breakglass.WithGrantID(ctx, grantID)
+	}
+
+	h.mux.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func (h *HTTPHandler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
+// authenticate verifies the bearer token and stores the caller in the
+// request context.
+func (h *HTTPHandler) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	if h.verifier == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.BearerToken(r.Header.Get("Authorization"))
+		if err != nil {
+			writeError(w, status.New(codes.Unauthenticated, err.Error()))
+			return
+		}
+
+		principal, err := h.verifier.Verify(token)
+		if err != nil {
+			h.log(r.Context()).Warn("Rejected token", "error", err)
+			writeError(w, status.New(codes.Unauthenticated, "invalid token"))
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// shed admits requests up to the concurrency limit, by the priority in the
+// X-Request-Priority header, and rejects the rest with 503.
+func (h *HTTPHandler) shed(method string, next http.HandlerFunc) http.HandlerFunc {
+	if h.loadShedder == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		priority := loadshed.ParsePriority(r.Header.Get(loadshed.PriorityHeader))
+
+		token, ok := h.loadShedder.Acquire(priority)
+		if !ok {
+			metrics.RequestsShed.WithLabelValues(method, priority.String()).Inc()
+			h.log(r.Context()).Warn("Request shed", "priority", priority.String())
+			writeError(w, status.New(codes.Unavailable, "server is overloaded, retry later"))
+			return
+		}
+
+		// A panicking handler releases its slot without affecting the limit
+		outcome := loadshed.Ignored
+		defer func() { token.Release(outcome) }()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next(rec, r)
+
+		outcome = loadshed.Succeeded
+		switch {
+		case r.Context().Err() != nil:
+			outcome = loadshed.Ignored
+		case rec.code == http.StatusServiceUnavailable || rec.code == http.StatusGatewayTimeout:
+			outcome = loadshed.Overloaded
+		case rec.code == http.StatusTooManyRequests || rec.code == http.StatusUnauthorized || rec.code == http.StatusForbidden:
+			outcome = loadshed.Ignored
+		}
+	}
+}
+
+// statusRecorder remembers the status code written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// rateLimit applies the rate limit policy of method to the caller and the
+// user ID in the path.
+func (h *HTTPHandler) rateLimit(method string, next http.HandlerFunc) http.HandlerFunc {
+	if h.rateLimits == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller := ratelimit.CallerKey(auth.PrincipalFromContext(r.Context()), r.RemoteAddr)
+
+		rejection, err := h.rateLimits.Check(r.Context(), method, caller, r.PathValue("user_id"))
+		if err != nil {
+			h.log(r.Context()).Error("Rate limit check failed", "error", err)
+			next(w, r)
+			return
+		}
+		if rejection != nil {
+			metrics.RateLimitRejected.WithLabelValues(method, rejection.Dimension).Inc()
+			h.log(r.Context()).Warn("Rate limit exceeded",
+				"caller", caller, "dimension", rejection.Dimension, "retry_after", rejection.RetryAfter)
+
+			seconds := int(math.Ceil(rejection.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+			writeError(w, status.Newf(codes.ResourceExhausted, "rate limit exceeded for %s, retry later", rejection.Dimension))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (h *HTTPHandler) getProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+
+	profile, err := h.profileService.GetUserProfile(r.Context(), userID)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP GetUserProfile failed", "user_id", userID, "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func (h *HTTPHandler) createProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+
+	var body createProfileBody
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	req := &models.CreateProfileRequest{
+		UserID:      userID,
+		FirstName:   body.FirstName,
+		LastName:    body.LastName,
+		Phone:       body.Phone,
+		DateOfBirth: body.DateOfBirth,
+	}
+
+	profile, err := h.profileService.CreateUserProfile(r.Context(), req)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP CreateUserProfile failed", "user_id", userID, "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newProfileResponse(profile))
+}
+
+func (h *HTTPHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+
+	var body updateProfileBody
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	req := body.request(userID)
+
+	profile, err := h.profileService.UpdateUserProfile(r.Context(), userID, req)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP UpdateUserProfile failed", "user_id", userID, "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func (h *HTTPHandler) deleteProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("user_id")
+
+	if err := h.profileService.DeleteUserProfile(r.Context(), userID); err != nil {
+		h.log(r.Context()).Warn("HTTP DeleteUserProfile failed", "user_id", userID, "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deleteResponse{Success: true})
+}
+
+func (h *HTTPHandler) findByPhone(w http.ResponseWriter, r *http.Request) {
+	var req findByPhoneRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	profile, err := h.profileService.FindProfileByPhone(r.Context(), req.Phone, "")
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP FindProfileByPhone failed", "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func (h *HTTPHandler) findByLicense(w http.ResponseWriter, r *http.Request) {
+	var req findByLicenseRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	profile, err := h.profileService.FindProfileByLicense(r.Context(), req.DrivingLicense, "")
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP FindProfileByLicense failed", "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func (h *HTTPHandler) queryAccessLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAccessLogFilter(r)
+	if err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	page, err := h.accessLog.QueryAccessLog(r.Context(), filter)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP QueryAccessLog failed", "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, accessLogResponse{Records: page.Records, NextBeforeID: page.NextBeforeID})
+}
+
+func (h *HTTPHandler) requestBreakGlass(w http.ResponseWriter, r *http.Request) {
+	var req models.BreakGlassRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	if h.breakGlass == nil {
+		writeError(w, statusFromError(service.ErrBreakGlassUnavailable))
+		return
+	}
+
+	grant, err := h.breakGlass.RequestBreakGlass(r.Context(), &req)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP RequestBreakGlass failed", "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, breakGlassResponse{Grant: grant})
+}
+
+func (h *HTTPHandler) revokeBreakGlass(w http.ResponseWriter, r *http.Request) {
+	grantID := r.PathValue("grant_id")
+	if h.breakGlass == nil {
+		writeError(w, statusFromError(service.ErrBreakGlassUnavailable))
+		return
+	}
+
+	grant, err := h.breakGlass.RevokeBreakGlass(r.Context(), grantID)
+	if err != nil {
+		h.log(r.Context()).Warn("HTTP RevokeBreakGlass failed", "grant_id", grantID, "error", err)
+		writeError(w, statusFromError(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, breakGlassResponse{Grant: grant})
+}
+
+func parseAccessLogFilter(r *http.Request) (models.AccessLogFilter, error) {
+	q := r.URL.Query()
+	filter := models.AccessLogFilter{
+		UserID: q.Get("user_id"),
+		Caller: q.Get("caller"),
+	}
+
+	var err error
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(name); v != "" {
+			if *dst, err = time.Parse(time.RFC3339, v); err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+		}
+	}
+	if v := q.Get("break_glass"); v != "" {
+		if filter.BreakGlass, err = strconv.ParseBool(v); err != nil {
+			return filter, errors.New("break_glass must be true or false")
+		}
+	}
+	if v := q.Get("before_id"); v != "" {
+		if filter.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, errors.New("before_id must be an integer")
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+	}
+	return filter, nil
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, st *status.Status) {
+	writeJSON(w, httpStatusFromCode(st.Code()), errorResponse{
+		Error: errorBody{
+			Code:    codeName(st.Code()),
+			Message: st.Message(),
+		},
+	})
+}
+
+// httpStatusFromCode follows the mapping used by grpc-gateway.
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeName renders a gRPC code the way Google APIs do, e.g. NOT_FOUND.
+func codeName(code codes.Code) string {
+	var b strings.Builder
+	prev := rune(0)
+	for _, r := range code.String() {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return b.String()
+}
@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/loadshed"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testUserID = "b2a4c6d8-1e3f-4a5b-8c7d-9e0f1a2b3c4d"
+
+// fakeProfiles serves a single in-memory profile and records the requests it
+// receives.
+type fakeProfiles struct {
+	profile *models.UserProfile
+	created *models.CreateProfileRequest
+	updated *models.UpdateProfileRequest
+	deleted string
+}
+
+func newFakeProfiles() *fakeProfiles {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	return &fakeProfiles{
+		profile: &models.UserProfile{
+			ID:             "6f1c2a9e-8d4b-4c1e-9a7f-2b3c4d5e6f70",
+			UserID:         testUserID,
+			FirstName:      "Anna",
+			LastName:       "Kowalska",
+			Phone:          "+48123456789",
+			DateOfBirth:    "1990-05-17",
+			Address:        "ul. Marszalkowska 10/12",
+			DrivingLicense: "PL-12345678",
+			CreatedAt:      created,
+			UpdatedAt:      created,
+		},
+	}
+}
+
+func (f *fakeProfiles) lookup(userID string) (*models.UserProfile, error) {
+	if f.profile == nil || userID != f.profile.UserID {
+		return nil, service.ErrProfileNotFound
+	}
+	return f.profile, nil
+}
+
+func (f *fakeProfiles) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	return f.lookup(userID)
+}
+
+func (f *fakeProfiles) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error) {
+	f.created = req
+	return &models.UserProfile{UserID: req.UserID, FirstName: req.FirstName, LastName: req.LastName}, nil
+}
+
+func (f *fakeProfiles) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	f.updated = req
+	return f.lookup(userID)
+}
+
+func (f *fakeProfiles) DeleteUserProfile(ctx context.Context, userID string) error {
+	if _, err := f.lookup(userID); err != nil {
+		return err
+	}
+	f.deleted = userID
+	return nil
+}
+
+func (f *fakeProfiles) FindProfileByPhone(ctx context.Context, phone, ownerID string) (*models.UserProfile, error) {
+	if phone != f.profile.Phone {
+		return nil, service.ErrProfileNotFound
+	}
+	return f.profile, nil
+}
+
+func (f *fakeProfiles) FindProfileByLicense(ctx context.Context, license, ownerID string) (*models.UserProfile, error) {
+	if license != f.profile.DrivingLicense {
+		return nil, service.ErrProfileNotFound
+	}
+	return f.profile, nil
+}
+
+const testGrantID = "0b7e2f4c-5a6d-4e8f-9a1b-2c3d4e5f6a7b"
+
+// fakeBreakGlass issues grants to "agent" and revokes only testGrantID.
+type fakeBreakGlass struct{}
+
+func (fakeBreakGlass) RequestBreakGlass(ctx context.Context, req *models.BreakGlassRequest) (*models.BreakGlassGrant, error) {
+	now := time.Now()
+	return &models.BreakGlassGrant{
+		ID:        testGrantID,
+		Subject:   "agent",
+		UserID:    req.UserID,
+		Ticket:    req.Ticket,
+		Reason:    req.Reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(15 * time.Minute),
+	}, nil
+}
+
+func (fakeBreakGlass) RevokeBreakGlass(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	if id != testGrantID {
+		return nil, service.ErrGrantNotFound
+	}
+	now := time.Now()
+	return &models.BreakGlassGrant{
+		ID:        testGrantID,
+		Subject:   "agent",
+		UserID:    testUserID,
+		Ticket:    "INC-1",
+		Reason:    "fraud investigation",
+		CreatedAt: now.Add(-time.Minute),
+		ExpiresAt: now.Add(14 * time.Minute),
+		RevokedAt: &now,
+	}, nil
+}
+
+// fakeAccessLog returns a single page without records.
+type fakeAccessLog struct{}
+
+func (fakeAccessLog) QueryAccessLog(ctx context.Context, filter models.AccessLogFilter) (*models.AccessLogPage, error) {
+	return &models.AccessLogPage{}, nil
+}
+
+func newTestHTTPHandler(profiles service.Profiles) *HTTPHandler {
+	return NewHTTPHandler(profiles, fakeAccessLog{}, fakeBreakGlass{}, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func serve(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeResponse[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestHTTPGetProfile(t *testing.T) {
+	h := newTestHTTPHandler(newFakeProfiles())
+
+	rec := serve(t, h, http.MethodGet, "/v1/profiles/"+testUserID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if rec.Header().Get(requestIDHeader) == "" {
+		t.Errorf("response has no %s header", requestIDHeader)
+	}
+
+	resp := decodeResponse[map[string]map[string]any](t, rec)
+	profile := resp["profile"]
+	if profile["user_id"] != testUserID || profile["first_name"] != "Anna" {
+		t.Errorf("profile = %v, want user %s named Anna", profile, testUserID)
+	}
+	// The gateway exposes the fields of the gRPC UserProfile message only
+	for _, field := range []string{"id", "address", "driving_license", "city", "country", "postal_code"} {
+		if _, ok := profile[field]; ok {
+			t.Errorf("profile exposes %q", field)
+		}
+	}
+}
+
+func TestHTTPGetProfileNotFound(t *testing.T) {
+	h := newTestHTTPHandler(newFakeProfiles())
+
+	rec := serve(t, h, http.MethodGet, "/v1/profiles/00000000-0000-0000-0000-000000000000", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	resp := decodeResponse[errorResponse](t, rec)
+	if resp.Error.Code != "NOT_FOUND" {
+		t.Errorf("error code = %q, want NOT_FOUND", resp.Error.Code)
+	}
+}
+
+func TestHTTPCreateProfileTakesUserIDFromPath(t *testing.T) {
+	profiles := newFakeProfiles()
+	h := newTestHTTPHandler(profiles)
+
+	rec := serve(t, h, http.MethodPost, "/v1/profiles/"+testUserID, `{"first_name":"Jan","last_name":"Nowak"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if profiles.created == nil || profiles.created.UserID != testUserID {
+		t.Errorf("created request = %+v, want user ID %s", profiles.created, testUserID)
+	}
+}
+
+func TestHTTPCreateProfileRejectsBadBodies(t *testing.T) {
+	tests := map[string]string{
+		"user_id in body": `{"user_id":"00000000-0000-0000-0000-000000000000","first_name":"Jan","last_name":"Nowak"}`,
+		"unknown field":   `{"first_name":"Jan","last_name":"Nowak","email":"jan@example.com"}`,
+		"malformed":       `{"first_name":`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			profiles := newFakeProfiles()
+			h := newTestHTTPHandler(profiles)
+
+			rec := serve(t, h, http.MethodPost, "/v1/profiles/"+testUserID, body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if resp := decodeResponse[errorResponse](t, rec); resp.Error.Code != "INVALID_ARGUMENT" {
+				t.Errorf("error code = %q, want INVALID_ARGUMENT", resp.Error.Code)
+			}
+			if profiles.created != nil {
+				t.Error("service called for an invalid request")
+			}
+		})
+	}
+}
+
+func TestHTTPUpdateProfile(t *testing.T) {
+	profiles := newFakeProfiles()
+	h := newTestHTTPHandler(profiles)
+
+	rec := serve(t, h, http.MethodPatch, "/v1/profiles/"+testUserID, `{"city":"Krakow"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if profiles.updated == nil || profiles.updated.UserID != testUserID || profiles.updated.City != "Krakow" {
+		t.Errorf("updated request = %+v, want city Krakow for %s", profiles.updated, testUserID)
+	}
+	if !slices.Equal(profiles.updated.Fields, []string{"city"}) {
+		t.Errorf("updated fields = %v, want [city]", profiles.updated.Fields)
+	}
+}
+
+func TestHTTPUpdateProfileClearsEmptyFields(t *testing.T) {
+	profiles := newFakeProfiles()
+	h := newTestHTTPHandler(profiles)
+
+	rec := serve(t, h, http.MethodPatch, "/v1/profiles/"+testUserID, `{"address":"","avatar_url":"https://example.com/a.png"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	req := profiles.updated
+	if !req.Updates("address") || req.Address != "" || !req.Updates("avatar_url") {
+		t.Errorf("updated request = %+v, want address cleared and avatar_url set", req)
+	}
+	if req.Updates("phone") || req.Updates("driving_license") {
+		t.Errorf("updated fields = %v, want fields missing from the body kept", req.Fields)
+	}
+}
+
+func TestHTTPDeleteProfile(t *testing.T) {
+	profiles := newFakeProfiles()
+	h := newTestHTTPHandler(profiles)
+
+	rec := serve(t, h, http.MethodDelete, "/v1/profiles/"+testUserID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if resp := decodeResponse[deleteResponse](t, rec); !resp.Success {
+		t.Error("success = false, want true")
+	}
+	if profiles.deleted != testUserID {
+		t.Errorf("deleted %q, want %q", profiles.deleted, testUserID)
+	}
+}
+
+func TestHTTPUnknownRoute(t *testing.T) {
+	h := newTestHTTPHandler(newFakeProfiles())
+
+	rec := serve(t, h, http.MethodPut, "/v1/profiles/"+testUserID, "{}")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHTTPAuthenticatesBeforeShedding(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{PublicKeyFiles: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := loadshed.NewLimiter(loadshed.Options{InitialLimit: 1, MinLimit: 1, MaxLimit: 1, TargetLatency: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHTTPHandler(newFakeProfiles(), fakeAccessLog{}, fakeBreakGlass{}, verifier, nil, limiter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// With the only slot taken, a request that reached the shedder would
+	// get 503
+	if _, ok := limiter.Acquire(loadshed.PriorityCritical); !ok {
+		t.Fatal("limiter is full")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/profiles/"+testUserID, nil)
+	req.Header.Set(loadshed.PriorityHeader, "critical")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if _, inflight := limiter.State(); inflight != 1 {
+		t.Errorf("inflight = %d, want the unauthenticated request never admitted", inflight)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/service"
+	"github.com/Brrocat/user-profile-service/proto/profilelookup"
+	"log/slog"
+)
+
+// LookupHandler serves ProfileLookupService. The searched values are
+// personal data and are never logged.
+type LookupHandler struct {
+	profilelookup.UnimplementedProfileLookupServiceServer
+	profileService service.Profiles
+	logger         *slog.Logger
+}
+
+func NewLookupHandler(profileService service.Profiles, logger *slog.Logger) *LookupHandler {
+	return &LookupHandler{
+		profileService: profileService,
+		logger:         logger,
+	}
+}
+
+func (h *LookupHandler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
+func (h *LookupHandler) FindProfileByPhone(ctx context.Context, req *profilelookup.FindProfileByPhoneRequest) (*profilelookup.FindProfileResponse, error) {
+	profile, err := h.profileService.FindProfileByPhone(ctx, req.Phone, "")
+	if err != nil {
+		h.log(ctx).Warn("FindProfileByPhone failed", "error", err)
+		return nil, statusFromError(err).Err()
+	}
+
+	return &profilelookup.FindProfileResponse{Profile: protoProfile(profile)}, nil
+}
+
+func (h *LookupHandler) FindProfileByLicense(ctx context.Context, req *profilelookup.FindProfileByLicenseRequest) (*profilelookup.FindProfileResponse, error) {
+	profile, err := h.profileService.FindProfileByLicense(ctx, req.DrivingLicense, "")
+	if err != nil {
+		h.log(ctx).Warn("FindProfileByLicense failed", "error", err)
+		return nil, statusFromError(err).Err()
+	}
+
+	return &profilelookup.FindProfileResponse{Profile: protoProfile(profile)}, nil
+}
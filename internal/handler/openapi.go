@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// buildOpenAPI renders an OpenAPI 3 document for the gateway routes. Schemas
+// are derived from the JSON tags of the request and response types. Secured
+// documents require a bearer JWT on every operation.
+func buildOpenAPI(routes []route, secured bool) map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	errorRef := schemaRef(reflect.TypeFor[errorResponse](), schemas)
+
+	for _, rt := range routes {
+		op := map[string]any{
+			"operationId": rt.operationID,
+			"summary":     rt.summary,
+			"responses": map[string]any{
+				strconv.Itoa(rt.status): map[string]any{
+					"description": http.StatusText(rt.status),
+					"content":     jsonContent(schemaRef(reflect.TypeOf(rt.response), schemas)),
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     jsonContent(errorRef),
+				},
+			},
+		}
+
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+			params = append(params, map[string]any{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, name := range rt.query {
+			params = append(params, map[string]any{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaRef(reflect.TypeOf(rt.request), schemas)),
+			}
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]any{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	components := map[string]any{
+		"schemas": schemas,
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "User Profile Service",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+
+	if secured {
+		components["securitySchemes"] = map[string]any{
+			"bearerAuth": map[string]any{
+				"type":         "http",
+				"scheme":       "bearer",
+				"bearerFormat": "JWT",
+			},
+		}
+		doc["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+	}
+
+	return doc
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// schemaRef returns a $ref to the component schema of struct type t,
+// registering it and the structs it references on first use.
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	name := schemaName(t)
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+	// Reserve the name before recursing so self references terminate
+	schemas[name] = nil
+
+	properties := map[string]any{}
+	var required []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		properties[jsonName] = fieldSchema(field.Type, schemas)
+		if strings.Contains(field.Tag.Get("validate"), "required") {
+			required = append(required, jsonName)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	schemas[name] = schema
+
+	return ref
+}
+
+func fieldSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": fieldSchema(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct:
+		return schemaRef(t, schemas)
+	default:
+		return map[string]any{}
+	}
+}
+
+// schemaName turns Go type names into exported schema names, e.g.
+// profileResponse becomes ProfileResponse.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sampleValues are used for request properties whose value matters to the
+// fake service.
+var sampleValues = map[string]any{
+	"phone":           "+48123456789",
+	"driving_license": "PL-12345678",
+	"user_id":         testUserID,
+	"date_of_birth":   "1990-05-17",
+	"ttl":             "15m",
+}
+
+// TestOpenAPIMatchesHandlers calls every operation in the served document
+// with a body built from its request schema, and checks that the handler
+// accepts it and answers with the documented status and schema.
+func TestOpenAPIMatchesHandlers(t *testing.T) {
+	h := newTestHTTPHandler(newFakeProfiles())
+
+	rec := serve(t, h, http.MethodGet, "/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d", rec.Code)
+	}
+	doc := decodeResponse[map[string]any](t, rec)
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+	operations := 0
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, raw := range item.(map[string]any) {
+			operations++
+			op := raw.(map[string]any)
+			name := op["operationId"].(string)
+
+			t.Run(name, func(t *testing.T) {
+				target := strings.NewReplacer("{user_id}", testUserID, "{grant_id}", testGrantID).Replace(path)
+
+				body := ""
+				if reqBody, ok := op["requestBody"].(map[string]any); ok {
+					schema := resolve(t, contentSchema(reqBody), schemas)
+					for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+						if _, ok := schema["properties"].(map[string]any)[m[1]]; ok {
+							t.Errorf("request body documents path parameter %s", m[1])
+						}
+					}
+					data, err := json.Marshal(sampleObject(schema))
+					if err != nil {
+						t.Fatal(err)
+					}
+					body = string(data)
+				}
+
+				rec := serve(t, h, strings.ToUpper(method), target, body)
+
+				var documented string
+				for code := range op["responses"].(map[string]any) {
+					if code != "default" {
+						documented = code
+					}
+				}
+				if strconv.Itoa(rec.Code) != documented {
+					t.Fatalf("%s %s status = %d, want documented %s: %s", method, path, rec.Code, documented, rec.Body)
+				}
+
+				response := op["responses"].(map[string]any)[documented].(map[string]any)
+				checkSchema(t, "response", decodeResponse[any](t, rec), contentSchema(response), schemas)
+			})
+		}
+	}
+
+	if want := len(h.routes()); operations != want {
+		t.Errorf("document has %d operations, want %d routes", operations, want)
+	}
+}
+
+func contentSchema(v map[string]any) map[string]any {
+	content := v["content"].(map[string]any)["application/json"].(map[string]any)
+	return content["schema"].(map[string]any)
+}
+
+func resolve(t *testing.T, schema map[string]any, schemas map[string]any) map[string]any {
+	t.Helper()
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	resolved, ok := schemas[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]any)
+	if !ok {
+		t.Fatalf("unresolved schema reference %s", ref)
+	}
+	return resolved
+}
+
+// sampleObject fills every property of an object schema.
+func sampleObject(schema map[string]any) map[string]any {
+	obj := map[string]any{}
+	for name, raw := range schema["properties"].(map[string]any) {
+		prop := raw.(map[string]any)
+		switch {
+		case sampleValues[name] != nil:
+			obj[name] = sampleValues[name]
+		case prop["format"] == "date-time":
+			obj[name] = "2024-03-01T09:30:00Z"
+		case prop["type"] == "integer":
+			obj[name] = 1
+		case prop["type"] == "boolean":
+			obj[name] = true
+		default:
+			obj[name] = name
+		}
+	}
+	return obj
+}
+
+// checkSchema fails when value has properties its schema does not document.
+func checkSchema(t *testing.T, at string, value any, schema map[string]any, schemas map[string]any) {
+	t.Helper()
+	schema = resolve(t, schema, schemas)
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop, ok := properties[key].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s is not in the schema", at, key)
+				continue
+			}
+			checkSchema(t, at+"."+key, v[key], prop, schemas)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			checkSchema(t, at+"["+strconv.Itoa(i)+"]", item, items, schemas)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package health reports service and dependency status over the standard
+// grpc.health.v1 protocol.
+package health
+
+import (
+	"context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type dependency struct {
+	name     string
+	critical bool
+	ping     func(ctx context.Context) error
+	healthy  bool
+}
+
+// Checker pings dependencies periodically and publishes one health status
+// per dependency plus an overall status for the given services. The overall
+// status is SERVING only while the checker is marked ready and every critical
+// dependency is healthy.
+type Checker struct {
+	server   *health.Server
+	services []string
+	interval time.Duration
+	timeout  time.Duration
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	ready bool
+	deps  []*dependency
+}
+
+func NewChecker(interval, timeout time.Duration, logger *slog.Logger, services ...string) *Checker {
+	c := &Checker{
+		server:   health.NewServer(),
+		services: append([]string{""}, services...),
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+	}
+	c.publish()
+	return c
+}
+
+// Server returns the grpc.health.v1 implementation to register.
+func (c *Checker) Server() healthpb.HealthServer {
+	return c.server
+}
+
+// AddDependency registers a dependency reported under its own service name.
+// A failing critical dependency also flips the overall status.
+func (c *Checker) AddDependency(name string, critical bool, ping func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deps = append(c.deps, &dependency{name: name, critical: critical, ping: ping})
+	c.server.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// SetReady marks whether the service may receive traffic, e.g. false during
+// startup and shutdown.
+func (c *Checker) SetReady(ready bool) {
+	c.mu.Lock()
+	c.ready = ready
+	c.mu.Unlock()
+
+	c.publish()
+}
+
+// Run checks all dependencies immediately and then every interval until ctx
+// is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckNow(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckNow pings every dependency once and publishes the result.
+func (c *Checker) CheckNow(ctx context.Context) {
+	c.mu.Lock()
+	deps := c.deps
+	c.mu.Unlock()
+
+	for _, dep := range deps {
+		pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := dep.ping(pingCtx)
+		cancel()
+
+		c.mu.Lock()
+		changed := dep.healthy != (err == nil)
+		dep.healthy = err == nil
+		c.mu.Unlock()
+
+		if changed && err != nil {
+			c.logger.Warn("Dependency unhealthy", "dependency", dep.name, "error", err)
+		} else if changed {
+			c.logger.Info("Dependency healthy", "dependency", dep.name)
+		}
+	}
+
+	c.publish()
+}
+
+// Shutdown reports NOT_SERVING for everything and ignores later updates.
+func (c *Checker) Shutdown() {
+	c.SetReady(false)
+	c.server.Shutdown()
+}
+
+func (c *Checker) publish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	serving := c.ready
+	for _, dep := range c.deps {
+		c.server.SetServingStatus(dep.name, servingStatus(dep.healthy))
+		if dep.critical && !dep.healthy {
+			serving = false
+		}
+	}
+
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, servingStatus(serving))
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"errors"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func status(t *testing.T, c *Checker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := c.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q): %v", service, err)
+	}
+	return resp.Status
+}
+
+func TestCheckerOverallStatus(t *testing.T) {
+	var postgresErr, redisErr error
+	c := NewChecker(time.Minute, time.Second, testLogger, "userprofile.UserProfileService")
+	c.AddDependency("postgres", true, func(ctx context.Context) error { return postgresErr })
+	c.AddDependency("redis", false, func(ctx context.Context) error { return redisErr })
+	ctx := context.Background()
+
+	c.CheckNow(ctx)
+	if got := status(t, c, "userprofile.UserProfileService"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("before ready = %s, want NOT_SERVING", got)
+	}
+
+	c.SetReady(true)
+	if got := status(t, c, ""); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("ready with healthy dependencies = %s, want SERVING", got)
+	}
+
+	// A non-critical dependency only reports itself
+	redisErr = errors.New("connection refused")
+	c.CheckNow(ctx)
+	if got := status(t, c, "redis"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("redis = %s, want NOT_SERVING", got)
+	}
+	if got := status(t, c, "userprofile.UserProfileService"); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("with redis down = %s, want SERVING", got)
+	}
+
+	postgresErr = errors.New("connection refused")
+	c.CheckNow(ctx)
+	if got := status(t, c, "userprofile.UserProfileService"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("with postgres down = %s, want NOT_SERVING", got)
+	}
+
+	postgresErr = nil
+	c.CheckNow(ctx)
+	if got := status(t, c, ""); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("after postgres recovered = %s, want SERVING", got)
+	}
+
+	c.Shutdown()
+	if got := status(t, c, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("after shutdown = %s, want NOT_SERVING", got)
+	}
+	c.SetReady(true)
+	if got := status(t, c, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("ready after shutdown = %s, want NOT_SERVING", got)
+	}
+}
+
+func TestCheckerTimesOutPings(t *testing.T) {
+	c := NewChecker(time.Minute, 10*time.Millisecond, testLogger)
+	c.AddDependency("postgres", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.SetReady(true)
+
+	c.CheckNow(context.Background())
+	if got := status(t, c, "postgres"); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("hanging dependency = %s, want NOT_SERVING", got)
+	}
+}
@@ -0,0 +1,111 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+)
+
+// publicMethodPrefixes are reachable without a token so probes keep working.
+var publicMethodPrefixes = []string{
+	"/grpc.health.v1.Health/",
+}
+
+// Auth verifies the bearer token in the authorization metadata and stores the
+// caller in the context for the authorization layer. On mutual TLS
+// connections the verified client certificate identity is added to the
+// caller, and authenticates it on its own when no token is sent.
+func Auth(verifier *auth.Verifier, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, verifier, logger, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth authenticates streaming calls, such as server reflection, like
+// Auth does for unary calls.
+func StreamAuth(verifier *auth.Verifier, logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), verifier, logger, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream carries the context holding the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, verifier *auth.Verifier, logger *slog.Logger, method string) (context.Context, error) {
+	for _, prefix := range publicMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			header = values[0]
+		}
+	}
+
+	certIdentity := peerCertIdentity(ctx)
+
+	token, err := auth.BearerToken(header)
+	if err != nil && certIdentity != "" {
+		principal := &auth.Principal{Subject: certIdentity, CertIdentity: certIdentity}
+		return auth.WithPrincipal(ctx, principal), nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	principal, err := verifier.Verify(token)
+	if err != nil {
+		logging.FromContext(ctx, logger).Warn("Rejected token", "method", method, "error", err)
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	principal.CertIdentity = certIdentity
+
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// peerCertIdentity returns the URI SAN, such as a SPIFFE ID, or else the
+// common name of the verified client certificate of the connection.
+func peerCertIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.CommonName
+}
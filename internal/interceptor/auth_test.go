@@ -0,0 +1,136 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testMethod = "/userprofile.UserProfileService/GetUserProfile"
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestVerifier returns a verifier trusting a fresh key and a token for
+// subject user-1 signed with it.
+func newTestVerifier(t *testing.T) (*auth.Verifier, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{PublicKeyFiles: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier, token
+}
+
+func withAuthorization(value string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", value))
+}
+
+func TestAuth(t *testing.T) {
+	verifier, token := newTestVerifier(t)
+	intercept := Auth(verifier, testLogger)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		method      string
+		wantCode    codes.Code
+		wantSubject string
+	}{
+		{"valid token", withAuthorization("Bearer " + token), testMethod, codes.OK, "user-1"},
+		{"missing token", context.Background(), testMethod, codes.Unauthenticated, ""},
+		{"invalid token", withAuthorization("Bearer " + token + "x"), testMethod, codes.Unauthenticated, ""},
+		{"public health check", context.Background(), "/grpc.health.v1.Health/Check", codes.OK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal *auth.Principal
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			_, err := intercept(tt.ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				principal = auth.PrincipalFromContext(ctx)
+				return nil, nil
+			})
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %s, want %s", code, tt.wantCode)
+			}
+			var subject string
+			if principal != nil {
+				subject = principal.Subject
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamAuth(t *testing.T) {
+	verifier, token := newTestVerifier(t)
+	intercept := StreamAuth(verifier, testLogger)
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}
+
+	called := false
+	err := intercept(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv any, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+	if status.Code(err) != codes.Unauthenticated || called {
+		t.Errorf("stream without token: code %s, handler called %t, want Unauthenticated and not called", status.Code(err), called)
+	}
+
+	var principal *auth.Principal
+	err = intercept(nil, &fakeServerStream{ctx: withAuthorization("Bearer " + token)}, info, func(srv any, ss grpc.ServerStream) error {
+		principal = auth.PrincipalFromContext(ss.Context())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream with token: %v", err)
+	}
+	if principal == nil || principal.Subject != "user-1" {
+		t.Errorf("principal = %+v, want subject user-1", principal)
+	}
+}
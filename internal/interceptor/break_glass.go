@@ -0,0 +1,21 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// BreakGlassGrant stores the x-break-glass-grant metadata in the context for
+// the authorization layer to check.
+func BreakGlassGrant() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(breakglass.GrantHeader); len(values) > 0 && values[0] != "" {
+				ctx = breakglass.WithGrantID(ctx, values[0])
+			}
+		}
+		return handler(ctx, req)
+	}
+}
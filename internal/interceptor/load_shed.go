@@ -0,0 +1,52 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/loadshed"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// LoadShed admits requests up to the adaptive concurrency limit and rejects
+// the rest with Unavailable, batch requests first. The priority is read from
+// the x-request-priority metadata. It must run after Auth so that only
+// authenticated callers can claim a priority. Shed requests are counted by
+// bare method name, like on the HTTP gateway.
+func LoadShed(limiter *loadshed.Limiter, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for _, prefix := range publicMethodPrefixes {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return handler(ctx, req)
+			}
+		}
+
+		priority := loadshed.PriorityDefault
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(loadshed.PriorityHeader); len(values) > 0 {
+				priority = loadshed.ParsePriority(values[0])
+			}
+		}
+
+		token, ok := limiter.Acquire(priority)
+		if !ok {
+			metrics.RequestsShed.WithLabelValues(path.Base(info.FullMethod), priority.String()).Inc()
+			logging.FromContext(ctx, logger).Warn("Request shed", "priority", priority.String())
+			return nil, status.Error(codes.Unavailable, "server is overloaded, retry later")
+		}
+
+		// A panicking handler releases its slot without affecting the limit
+		outcome := loadshed.Ignored
+		defer func() { token.Release(outcome) }()
+
+		resp, err := handler(ctx, req)
+		outcome = loadshed.OutcomeOf(status.Code(err))
+		return resp, err
+	}
+}
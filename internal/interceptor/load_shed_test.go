@@ -0,0 +1,37 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/loadshed"
+	"google.golang.org/grpc"
+	"testing"
+	"time"
+)
+
+func TestLoadShedReleasesSlotOnPanic(t *testing.T) {
+	limiter, err := loadshed.NewLimiter(loadshed.Options{InitialLimit: 10, MinLimit: 1, MaxLimit: 10, TargetLatency: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := LoadShed(limiter, testLogger)
+	info := &grpc.UnaryServerInfo{FullMethod: testMethod}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("handler panic was swallowed")
+			}
+		}()
+		intercept(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+			panic("boom")
+		})
+	}()
+
+	limit, inflight := limiter.State()
+	if inflight != 0 {
+		t.Errorf("inflight = %d after a panic, want 0", inflight)
+	}
+	if limit != 10 {
+		t.Errorf("limit = %g after a panic, want it unchanged at 10", limit)
+	}
+}
@@ -0,0 +1,25 @@
+// Package interceptor contains the gRPC server interceptors shared by the
+// gRPC port and the Connect/gRPC-Web bridge.
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Metrics records request counts and latency per method and status code.
+func Metrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err).String()
+		metrics.GRPCRequests.WithLabelValues(info.FullMethod, code).Inc()
+		metrics.GRPCDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
@@ -0,0 +1,21 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/audit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AccessPurpose stores the x-access-purpose metadata in the context, where
+// the access log picks it up.
+func AccessPurpose() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(audit.PurposeHeader); len(values) > 0 {
+				ctx = audit.WithPurpose(ctx, values[0])
+			}
+		}
+		return handler(ctx, req)
+	}
+}
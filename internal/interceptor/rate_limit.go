@@ -0,0 +1,67 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// RetryAfterHeader carries the number of seconds to wait before retrying a
+// rate limited request.
+const RetryAfterHeader = "retry-after"
+
+// RateLimit enforces the policy per caller and per target user
+// ID, keyed by the bare method name like on the HTTP gateway. It must run
+// after Auth so that callers are identified by their token. Rejected calls
+// return ResourceExhausted with retry-after metadata.
+func RateLimit(policy *ratelimit.Policy, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for _, prefix := range publicMethodPrefixes {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return handler(ctx, req)
+			}
+		}
+
+		var remoteAddr string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remoteAddr = p.Addr.String()
+		}
+		caller := ratelimit.CallerKey(auth.PrincipalFromContext(ctx), remoteAddr)
+
+		var userID string
+		if r, ok := req.(interface{ GetUserId() string }); ok {
+			userID = r.GetUserId()
+		}
+
+		method := path.Base(info.FullMethod)
+		rejection, err := policy.Check(ctx, method, caller, userID)
+		if err != nil {
+			// Limits protect the service but are not worth failing requests for
+			logging.FromContext(ctx, logger).Error("Rate limit check failed", "error", err)
+			return handler(ctx, req)
+		}
+		if rejection != nil {
+			metrics.RateLimitRejected.WithLabelValues(method, rejection.Dimension).Inc()
+			logging.FromContext(ctx, logger).Warn("Rate limit exceeded",
+				"caller", caller, "dimension", rejection.Dimension, "retry_after", rejection.RetryAfter)
+
+			seconds := int(math.Ceil(rejection.RetryAfter.Seconds()))
+			grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.Itoa(max(seconds, 1))))
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s, retry later", rejection.Dimension)
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,61 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestRecoveryReturnsInternal(t *testing.T) {
+	intercept := Recovery(testLogger)
+	info := &grpc.UnaryServerInfo{FullMethod: testMethod}
+
+	resp, err := intercept(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Errorf("panicking handler = %v, %v, want nil, Internal", resp, err)
+	}
+
+	resp, err = intercept(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	if resp != "ok" || err != nil {
+		t.Errorf("handler = %v, %v, want ok, nil", resp, err)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: testMethod}
+	deadline := func(ctx context.Context) (time.Duration, bool) {
+		var remaining time.Duration
+		var ok bool
+		_, _ = DefaultTimeout(time.Minute)(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			var d time.Time
+			d, ok = ctx.Deadline()
+			remaining = time.Until(d)
+			return nil, nil
+		})
+		return remaining, ok
+	}
+
+	if remaining, ok := deadline(context.Background()); !ok || remaining <= 0 || remaining > time.Minute {
+		t.Errorf("without a deadline = %v, %v, want at most a minute", remaining, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if remaining, ok := deadline(ctx); !ok || remaining <= time.Minute {
+		t.Errorf("with the caller's deadline = %v, %v, want it unchanged", remaining, ok)
+	}
+
+	_, _ = DefaultTimeout(0)(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("zero timeout set a deadline")
+		}
+		return nil, nil
+	})
+}
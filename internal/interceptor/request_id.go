@@ -0,0 +1,41 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+)
+
+// RequestIDHeader carries the correlation ID in request and response
+// metadata.
+const RequestIDHeader = "x-request-id"
+
+// RequestID propagates the caller's x-request-id, or assigns a new one, echoes
+// it in the response headers and attaches a logger carrying it to the context.
+func RequestID(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(RequestIDHeader); len(values) > 0 && logging.ValidRequestID(values[0]) {
+				id = values[0]
+			}
+		}
+		if id == "" {
+			id = logging.NewRequestID()
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+
+		attrs := []any{"request_id", id, "method", info.FullMethod}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			attrs = append(attrs, "trace_id", sc.TraceID().String())
+		}
+
+		ctx = logging.WithRequestID(ctx, id)
+		ctx = logging.WithLogger(ctx, logger.With(attrs...))
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,23 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// DefaultTimeout applies timeout to requests that arrive without a deadline,
+// so they cannot hold database connections forever. Deadlines set by the
+// caller are left unchanged.
+func DefaultTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
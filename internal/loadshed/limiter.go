@@ -0,0 +1,190 @@
+// Package loadshed bounds the number of requests in flight with a limit
+// that adapts to latency, and sheds requests beyond it by priority.
+package loadshed
+
+import (
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriorityHeader is the request metadata carrying the caller's priority.
+const PriorityHeader = "x-request-priority"
+
+// Priority decides how much of the concurrency limit a request may use.
+type Priority int
+
+const (
+	// PriorityBatch is for jobs that can retry later, such as exports.
+	PriorityBatch Priority = iota
+	// PriorityDefault is used when a request sends no priority.
+	PriorityDefault
+	// PriorityCritical is for reads on the path of an active rental.
+	PriorityCritical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return "batch"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "default"
+	}
+}
+
+// ParsePriority parses a priority header value. Unknown and empty values are
+// the default priority.
+func ParsePriority(value string) Priority {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "batch":
+		return PriorityBatch
+	case "critical":
+		return PriorityCritical
+	default:
+		return PriorityDefault
+	}
+}
+
+// shares are the fractions of the limit each priority may fill, so that
+// batch jobs are shed first and critical requests keep some headroom.
+var shares = map[Priority]float64{
+	PriorityBatch:    0.5,
+	PriorityDefault:  0.9,
+	PriorityCritical: 1,
+}
+
+// backoffRatio is the multiplicative decrease applied to the limit on each
+// slow or failed request.
+const backoffRatio = 0.9
+
+// Options configures a Limiter.
+type Options struct {
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+	// TargetLatency is the latency above which a request counts as a sign of
+	// overload.
+	TargetLatency time.Duration
+	// OnChange, if set, is called with the new limit and number of requests
+	// in flight whenever either changes.
+	OnChange func(limit float64, inflight int)
+}
+
+// Limiter is an AIMD concurrency limiter. Each request that completes within
+// the target latency raises the limit by one while the limit is in use, and
+// each slow or overloaded request lowers it by 10%.
+type Limiter struct {
+	mu       sync.Mutex
+	opts     Options
+	limit    float64
+	inflight int
+}
+
+func NewLimiter(opts Options) (*Limiter, error) {
+	if opts.MinLimit < 1 || opts.MaxLimit < opts.MinLimit {
+		return nil, fmt.Errorf("concurrency limits must satisfy 1 <= min <= max, got min %d and max %d", opts.MinLimit, opts.MaxLimit)
+	}
+	if opts.InitialLimit < opts.MinLimit || opts.InitialLimit > opts.MaxLimit {
+		return nil, fmt.Errorf("initial concurrency limit %d must be between %d and %d", opts.InitialLimit, opts.MinLimit, opts.MaxLimit)
+	}
+	if opts.TargetLatency <= 0 {
+		return nil, fmt.Errorf("target latency must be positive, got %s", opts.TargetLatency)
+	}
+
+	l := &Limiter{opts: opts, limit: float64(opts.InitialLimit)}
+	l.changed()
+	return l, nil
+}
+
+// Acquire admits a request of priority p if the requests in flight leave
+// room for it. The returned token must be released when the request
+// completes.
+func (l *Limiter) Acquire(p Priority) (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if float64(l.inflight) >= max(l.limit*shares[p], 1) {
+		return nil, false
+	}
+	l.inflight++
+	l.changed()
+	return &Token{limiter: l, start: time.Now()}, true
+}
+
+// State returns the current limit and number of requests in flight.
+func (l *Limiter) State() (limit float64, inflight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.inflight
+}
+
+func (l *Limiter) release(latency time.Duration, outcome Outcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	switch {
+	case outcome == Ignored:
+	case outcome == Overloaded || latency > l.opts.TargetLatency:
+		l.limit = max(l.limit*backoffRatio, float64(l.opts.MinLimit))
+	case float64(inflight)*2 >= l.limit:
+		// Only grow while at least half the limit is used, so that a quiet
+		// period does not leave a limit that was never tested
+		l.limit = min(l.limit+1, float64(l.opts.MaxLimit))
+	}
+	l.changed()
+}
+
+func (l *Limiter) changed() {
+	if l.opts.OnChange != nil {
+		l.opts.OnChange(l.limit, l.inflight)
+	}
+}
+
+// Outcome classifies a completed request for the limit.
+type Outcome int
+
+const (
+	// Succeeded requests adjust the limit by their latency.
+	Succeeded Outcome = iota
+	// Overloaded requests failed because a dependency was slow or
+	// unavailable, and lower the limit.
+	Overloaded
+	// Ignored requests, such as those cancelled by the client, say nothing
+	// about the load and leave the limit unchanged.
+	Ignored
+)
+
+// OutcomeOf classifies a request by its status code. Deadlines and
+// unavailable dependencies mean overload. Cancellations and rejections by
+// later interceptors say nothing about latency.
+func OutcomeOf(code codes.Code) Outcome {
+	switch code {
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return Overloaded
+	case codes.Canceled, codes.ResourceExhausted, codes.Unauthenticated, codes.PermissionDenied:
+		return Ignored
+	default:
+		return Succeeded
+	}
+}
+
+// Token is an admitted request.
+type Token struct {
+	limiter *Limiter
+	start   time.Time
+	once    sync.Once
+}
+
+// Release returns the token and adjusts the limit. Further calls are no-ops.
+func (t *Token) Release(outcome Outcome) {
+	t.once.Do(func() {
+		t.limiter.release(time.Since(t.start), outcome)
+	})
+}
@@ -0,0 +1,145 @@
+package loadshed
+
+import (
+	"google.golang.org/grpc/codes"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(t *testing.T, opts Options) *Limiter {
+	t.Helper()
+	l, err := NewLimiter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func acquire(t *testing.T, l *Limiter, p Priority, n int) []*Token {
+	t.Helper()
+	tokens := make([]*Token, 0, n)
+	for range n {
+		token, ok := l.Acquire(p)
+		if !ok {
+			t.Fatalf("%s request %d was shed", p, len(tokens)+1)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestAcquireSharesLimitByPriority(t *testing.T) {
+	l := newTestLimiter(t, Options{InitialLimit: 10, MinLimit: 1, MaxLimit: 10, TargetLatency: time.Hour})
+
+	// Batch requests fill half of the limit, default requests 90% and
+	// critical requests all of it
+	acquire(t, l, PriorityBatch, 5)
+	if _, ok := l.Acquire(PriorityBatch); ok {
+		t.Error("batch request admitted beyond half of the limit")
+	}
+	acquire(t, l, PriorityDefault, 4)
+	if _, ok := l.Acquire(PriorityDefault); ok {
+		t.Error("default request admitted beyond 90% of the limit")
+	}
+	acquire(t, l, PriorityCritical, 1)
+	if _, ok := l.Acquire(PriorityCritical); ok {
+		t.Error("critical request admitted beyond the limit")
+	}
+
+	if _, inflight := l.State(); inflight != 10 {
+		t.Errorf("inflight = %d, want 10", inflight)
+	}
+}
+
+func TestAcquireAdmitsOneRequestAtMinimum(t *testing.T) {
+	l := newTestLimiter(t, Options{InitialLimit: 1, MinLimit: 1, MaxLimit: 10, TargetLatency: time.Hour})
+
+	token := acquire(t, l, PriorityBatch, 1)[0]
+	if _, ok := l.Acquire(PriorityCritical); ok {
+		t.Error("second request admitted with a limit of one")
+	}
+	token.Release(Succeeded)
+	acquire(t, l, PriorityBatch, 1)
+}
+
+func TestLimitAdapts(t *testing.T) {
+	l := newTestLimiter(t, Options{InitialLimit: 10, MinLimit: 5, MaxLimit: 12, TargetLatency: time.Hour})
+	assertLimit := func(step string, want float64) {
+		t.Helper()
+		if limit, _ := l.State(); math.Abs(limit-want) > 1e-9 {
+			t.Errorf("limit after %s = %g, want %g", step, limit, want)
+		}
+	}
+
+	// Additive increase only while at least half the limit is in use
+	tokens := acquire(t, l, PriorityCritical, 6)
+	tokens[0].Release(Succeeded)
+	assertLimit("a fast request at high load", 11)
+	for _, token := range tokens[1:] {
+		token.Release(Ignored)
+	}
+	acquire(t, l, PriorityCritical, 1)[0].Release(Succeeded)
+	assertLimit("a fast request at low load", 11)
+
+	tokens = acquire(t, l, PriorityCritical, 9)
+	for _, token := range tokens[:3] {
+		token.Release(Succeeded)
+	}
+	assertLimit("growing past the maximum", 12)
+
+	// Multiplicative decrease down to the minimum
+	tokens[3].Release(Overloaded)
+	assertLimit("an overloaded request", 10.8)
+	tokens[4].Release(Ignored)
+	assertLimit("an ignored request", 10.8)
+	for _, token := range tokens[5:] {
+		token.Release(Overloaded)
+	}
+	assertLimit("repeated overload", 10.8*0.9*0.9*0.9*0.9)
+	for range 10 {
+		acquire(t, l, PriorityCritical, 1)[0].Release(Overloaded)
+	}
+	assertLimit("shrinking past the minimum", 5)
+
+	// Releasing a token twice has no effect
+	token := acquire(t, l, PriorityCritical, 1)[0]
+	token.Release(Ignored)
+	token.Release(Overloaded)
+	assertLimit("a second release", 5)
+	if _, inflight := l.State(); inflight != 0 {
+		t.Errorf("inflight = %d after all releases, want 0", inflight)
+	}
+}
+
+func TestSlowRequestsLowerLimit(t *testing.T) {
+	l := newTestLimiter(t, Options{InitialLimit: 10, MinLimit: 1, MaxLimit: 10, TargetLatency: time.Millisecond})
+
+	token := acquire(t, l, PriorityDefault, 1)[0]
+	time.Sleep(5 * time.Millisecond)
+	token.Release(Succeeded)
+
+	if limit, _ := l.State(); limit != 9 {
+		t.Errorf("limit after a slow request = %g, want 9", limit)
+	}
+}
+
+func TestParsePriorityAndOutcome(t *testing.T) {
+	for value, want := range map[string]Priority{
+		"critical": PriorityCritical, " Batch ": PriorityBatch, "default": PriorityDefault, "": PriorityDefault, "urgent": PriorityDefault,
+	} {
+		if got := ParsePriority(value); got != want {
+			t.Errorf("ParsePriority(%q) = %s, want %s", value, got, want)
+		}
+	}
+
+	for code, want := range map[codes.Code]Outcome{
+		codes.OK: Succeeded, codes.NotFound: Succeeded, codes.Internal: Succeeded,
+		codes.DeadlineExceeded: Overloaded, codes.Unavailable: Overloaded,
+		codes.Canceled: Ignored, codes.PermissionDenied: Ignored, codes.ResourceExhausted: Ignored,
+	} {
+		if got := OutcomeOf(code); got != want {
+			t.Errorf("OutcomeOf(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LevelController changes the level of a running logger and reverts the
+// change once its TTL expires. Every change is written to the audit logger.
+type LevelController struct {
+	level *slog.LevelVar
+	base  slog.Level
+	audit *slog.Logger
+
+	mu         sync.Mutex
+	expiresAt  time.Time
+	timer      *time.Timer
+	generation int
+}
+
+// LevelState describes the current and configured log levels. ExpiresAt is
+// zero when no override is active.
+type LevelState struct {
+	Level     slog.Level
+	Default   slog.Level
+	ExpiresAt time.Time
+}
+
+// NewLevelController takes the current value of level as the default to
+// revert to.
+func NewLevelController(level *slog.LevelVar, audit *slog.Logger) *LevelController {
+	return &LevelController{
+		level: level,
+		base:  level.Level(),
+		audit: audit,
+	}
+}
+
+func (c *LevelController) State() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return LevelState{
+		Level:     c.level.Level(),
+		Default:   c.base,
+		ExpiresAt: c.expiresAt,
+	}
+}
+
+// Set overrides the log level for ttl. A later Set replaces the previous
+// override and its expiry.
+func (c *LevelController) Set(level slog.Level, ttl time.Duration, actor, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.level.Level()
+	c.stopTimer()
+	c.level.Set(level)
+	c.expiresAt = time.Now().Add(ttl)
+
+	generation := c.generation
+	c.timer = time.AfterFunc(ttl, func() { c.expire(generation) })
+
+	c.audit.Info("Log level changed",
+		"level", level, "previous", previous, "ttl", ttl, "actor", actor, "reason", reason)
+}
+
+// Reset restores the default level immediately.
+func (c *LevelController) Reset(actor string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.level.Level()
+	c.stopTimer()
+	c.level.Set(c.base)
+	c.expiresAt = time.Time{}
+
+	c.audit.Info("Log level reset", "level", c.base, "previous", previous, "actor", actor)
+}
+
+func (c *LevelController) expire(generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A newer override or reset has already replaced this one
+	if generation != c.generation {
+		return
+	}
+
+	previous := c.level.Level()
+	c.stopTimer()
+	c.level.Set(c.base)
+	c.expiresAt = time.Time{}
+
+	c.audit.Info("Log level override expired", "level", c.base, "previous", previous)
+}
+
+// stopTimer cancels the pending revert. c.mu must be held.
+func (c *LevelController) stopTimer() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.generation++
+}
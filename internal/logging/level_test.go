@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelControllerExpires(t *testing.T) {
+	var level slog.LevelVar
+	var audit bytes.Buffer
+	c := NewLevelController(&level, slog.New(slog.NewTextHandler(&audit, nil)))
+
+	c.Set(slog.LevelDebug, 20*time.Millisecond, "ops", "incident 42")
+	if state := c.State(); state.Level != slog.LevelDebug || state.Default != slog.LevelInfo || state.ExpiresAt.IsZero() {
+		t.Fatalf("state after Set = %+v, want debug with an expiry", state)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for level.Level() != slog.LevelInfo {
+		if time.Now().After(deadline) {
+			t.Fatal("override did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state := c.State(); !state.ExpiresAt.IsZero() {
+		t.Errorf("ExpiresAt = %s after expiry, want zero", state.ExpiresAt)
+	}
+
+	for _, want := range []string{"Log level changed", "actor=ops", "incident 42", "Log level override expired"} {
+		if !strings.Contains(audit.String(), want) {
+			t.Errorf("audit log does not contain %q:\n%s", want, audit.String())
+		}
+	}
+}
+
+func TestLevelControllerReplacesOverride(t *testing.T) {
+	var level slog.LevelVar
+	c := NewLevelController(&level, slog.New(slog.DiscardHandler))
+
+	// The expiry of the first override must not revert the second
+	c.Set(slog.LevelDebug, 10*time.Millisecond, "ops", "")
+	c.Set(slog.LevelWarn, time.Hour, "ops", "")
+	time.Sleep(50 * time.Millisecond)
+	if got := level.Level(); got != slog.LevelWarn {
+		t.Fatalf("level = %s after the first override expired, want WARN", got)
+	}
+
+	c.Reset("ops")
+	if state := c.State(); state.Level != slog.LevelInfo || !state.ExpiresAt.IsZero() {
+		t.Errorf("state after Reset = %+v, want the default without expiry", state)
+	}
+}
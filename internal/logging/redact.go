@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const redacted = "[REDACTED]"
+
+// errorValuePattern matches the values that errors commonly quote, such as
+// `invalid input syntax for type uuid: "..."` from Postgres, Go's %q and
+// strconv errors, and the `Key (phone)=(...)` details of constraint errors.
+var errorValuePattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|\)=\([^)]*\)`)
+
+// sensitiveFragments lists the words, in the normalized form produced by
+// keyWords, that mark an attribute key or group name as personal data. A key
+// matches when its words contain one of the fragments, so "phone",
+// "user.phone", "phoneNumber" and "driver_license_number" all match.
+var sensitiveFragments = [][]string{
+	{"phone"},
+	{"date", "of", "birth"},
+	{"birth", "date"},
+	{"birthdate"},
+	{"dob"},
+	{"address"},
+	{"license"},
+	{"licence"},
+}
+
+// RedactHandler masks the values of sensitive attributes, including those
+// nested in groups and in values implementing slog.LogValuer, before passing
+// records on to the next handler. Every attribute inside a group with a
+// sensitive name is masked. Errors are logged as their message with quoted
+// values masked, since errors from drivers and parsers often quote the input.
+type RedactHandler struct {
+	next slog.Handler
+	// inSensitiveGroup is set once WithGroup opened a sensitive group.
+	inSensitiveGroup bool
+}
+
+func NewRedactHandler(next slog.Handler) *RedactHandler {
+	return &RedactHandler{next: next}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redact(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(clean), inSensitiveGroup: h.inSensitiveGroup}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{
+		next:             h.next.WithGroup(name),
+		inSensitiveGroup: h.inSensitiveGroup || isSensitiveKey(name),
+	}
+}
+
+func (h *RedactHandler) redact(a slog.Attr) slog.Attr {
+	if h.inSensitiveGroup {
+		return slog.String(a.Key, redacted)
+	}
+	return redactAttr(a)
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	if err, ok := a.Value.Any().(error); ok && a.Value.Kind() == slog.KindAny {
+		return slog.String(a.Key, redactError(err))
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			clean[i] = redactAttr(ga)
+		}
+		a.Value = slog.GroupValue(clean...)
+	}
+
+	return a
+}
+
+// redactError returns the message of err with quoted values masked.
+func redactError(err error) string {
+	return errorValuePattern.ReplaceAllStringFunc(err.Error(), func(value string) string {
+		if strings.HasPrefix(value, ")=(") {
+			return ")=(" + redacted + ")"
+		}
+		return `"` + redacted + `"`
+	})
+}
+
+func isSensitiveKey(key string) bool {
+	words := keyWords(key)
+	for _, fragment := range sensitiveFragments {
+		for i := 0; i+len(fragment) <= len(words); i++ {
+			if slices.Equal(words[i:i+len(fragment)], fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyWords splits a key into lower-case words at punctuation, digits and
+// camel-case boundaries, e.g. "user.driverLicense_2" becomes
+// ["user", "driver", "license"].
+func keyWords(key string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	prev := rune(0)
+	for _, r := range key {
+		switch {
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) {
+				flush()
+			}
+			word.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+
+	return words
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+	}{
+		{"exact key", func(l *slog.Logger) { l.Info("msg", "phone", "+48123456789") }},
+		{"dotted key", func(l *slog.Logger) { l.Info("msg", "user.phone", "+48123456789") }},
+		{"camel case key", func(l *slog.Logger) { l.Info("msg", "phoneNumber", "+48123456789") }},
+		{"longer key", func(l *slog.Logger) { l.Info("msg", "driver_license_number", "+48123456789") }},
+		{"nested group", func(l *slog.Logger) {
+			l.Info("msg", slog.Group("user", slog.Group("contact", slog.String("phone", "+48123456789"))))
+		}},
+		{"sensitive group", func(l *slog.Logger) {
+			l.Info("msg", slog.Group("home_address", slog.String("street", "+48123456789")))
+		}},
+		{"handler group", func(l *slog.Logger) { l.WithGroup("phone").Info("msg", "number", "+48123456789") }},
+		{"handler attrs", func(l *slog.Logger) { l.With("date_of_birth", "+48123456789").Info("msg") }},
+		{"quoted error value", func(l *slog.Logger) {
+			l.Info("msg", "error", fmt.Errorf("failed to find profile: %w", errors.New(`invalid input syntax for type uuid: "+48123456789"`)))
+		}},
+		{"constraint error detail", func(l *slog.Logger) {
+			l.Info("msg", "error", errors.New("duplicate key: Key (phone_index)=(+48123456789) already exists"))
+		}},
+		{"error with %q", func(l *slog.Logger) { l.Info("msg", "err", fmt.Errorf("invalid phone %q", "+48123456789")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil))))
+
+			if strings.Contains(buf.String(), "+48123456789") {
+				t.Errorf("personal data logged: %s", buf.String())
+			}
+			if !strings.Contains(buf.String(), redacted) {
+				t.Errorf("no redacted value logged: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestRedactHandlerKeepsOtherKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil)))
+
+	logger.Info("msg", "user_id", "42", "remote_addr", "10.0.0.1", "city_code", "WAW")
+
+	for _, want := range []string{"user_id=42", "remote_addr=10.0.0.1", "city_code=WAW"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log %q does not contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestRedactHandlerKeepsErrorMessages(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil)))
+
+	logger.Info("msg", "error", fmt.Errorf("failed to update profile: %w", errors.New("connection refused")))
+
+	if !strings.Contains(buf.String(), `error="failed to update profile: connection refused"`) {
+		t.Errorf("error message not logged: %s", buf.String())
+	}
+}
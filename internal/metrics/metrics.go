@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "user_profile"
+
+var (
+	CacheVerifyChecked = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_verify_checked_total",
+		Help:      "Cached profiles compared against the database.",
+	})
+
+	CacheVerifyMismatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_verify_mismatches_total",
+		Help:      "Cached profiles that differ from the database, by kind.",
+	}, []string{"kind"})
+
+	CacheVerifyRepairs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_verify_repairs_total",
+		Help:      "Cache entries repaired by the consistency checker, by action.",
+	}, []string{"action"})
+
+	CacheDriftRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_drift_ratio",
+		Help:      "Share of mismatching entries in the most recent verification run.",
+	})
+)
+
+var (
+	CacheWarmupProfiles = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_warmup_profiles_total",
+		Help:      "Profiles preloaded into the cache by the warm-up job.",
+	})
+
+	CacheWarmupProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_warmup_progress_ratio",
+		Help:      "Progress of the running or last cache warm-up, from 0 to 1.",
+	})
+
+	CacheWarmupDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_warmup_duration_seconds",
+		Help:      "Duration of the last completed cache warm-up.",
+	})
+)
+
+var (
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Handled gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC request latency by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+var (
+	CacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "Profile lookups answered by the cache, including negative entries.",
+	})
+
+	CacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Profile lookups not found in the cache, including stale entries.",
+	})
+
+	CacheErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_errors_total",
+		Help:      "Failed cache operations by operation.",
+	}, []string{"operation"})
+)
+
+var DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "db_query_duration_seconds",
+	Help:      "Database query latency by query.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"query"})
+
+var (
+	ProfilesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "profiles_created_total",
+		Help:      "Profiles created.",
+	})
+
+	ProfilesUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "profiles_updated_total",
+		Help:      "Profiles updated.",
+	})
+
+	ProfilesDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "profiles_deleted_total",
+		Help:      "Profiles deleted.",
+	})
+)
+
+var (
+	RateLimitRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejected_total",
+		Help:      "Requests rejected by the rate limiter, by method and limit dimension.",
+	}, []string{"method", "dimension"})
+
+	RateLimitErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_errors_total",
+		Help:      "Failed distributed rate limit checks that fell back to local limits.",
+	})
+)
+
+var (
+	ConcurrencyLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive limit of requests in flight.",
+	})
+
+	ConcurrencyInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "concurrency_inflight",
+		Help:      "Requests in flight admitted by the concurrency limiter.",
+	})
+
+	RequestsShed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_shed_total",
+		Help:      "Requests rejected by the concurrency limiter, by method and priority.",
+	}, []string{"method", "priority"})
+)
+
+var (
+	AuditRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_records_total",
+		Help:      "Access records queued for reads of personal data, by method.",
+	}, []string{"method"})
+
+	AuditRecordsLost = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_records_lost_total",
+		Help:      "Access records that were queued but never written, on shutdown.",
+	})
+
+	AuditWriterFailing = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "audit_writer_failing",
+		Help:      "1 while access records cannot be written and reads are refused.",
+	})
+
+	AuditRecordsPurged = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_records_purged_total",
+		Help:      "Access records deleted after the retention period.",
+	})
+
+	BreakGlassWebhooksLost = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "break_glass_webhooks_lost_total",
+		Help:      "Break-glass webhook notifications dropped on a full queue or that failed to send.",
+	})
+)
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports pgxpool statistics at scrape time.
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	constructingConn *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquireCount     *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	emptyAcquire     *prometheus.Desc
+	emptyAcquireWait *prometheus.Desc
+	canceledAcquire  *prometheus.Desc
+}
+
+func NewPoolCollector(stat func() *pgxpool.Stat) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &PoolCollector{
+		stat:             stat,
+		acquiredConns:    desc("acquired_connections", "Connections currently acquired from the pool."),
+		idleConns:        desc("idle_connections", "Idle connections in the pool."),
+		constructingConn: desc("constructing_connections", "Connections being established."),
+		totalConns:       desc("total_connections", "Total connections in the pool."),
+		maxConns:         desc("max_connections", "Maximum size of the pool."),
+		acquireCount:     desc("acquires_total", "Successful connection acquisitions."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquire:     desc("empty_acquires_total", "Acquisitions that had to wait for a connection."),
+		emptyAcquireWait: desc("empty_acquire_wait_seconds_total", "Total time spent waiting for a connection when the pool was empty."),
+		canceledAcquire:  desc("canceled_acquires_total", "Acquisitions cancelled by their context."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConn, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireWait, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+func TestPoolCollector(t *testing.T) {
+	// The pool connects lazily, so no database is needed
+	cfg, err := pgxpool.ParseConfig("postgres://user@127.0.0.1:1/profiles?pool_max_conns=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c := NewPoolCollector(pool.Stat)
+	if n := testutil.CollectAndCount(c); n != 10 {
+		t.Errorf("collected %d metrics, want 10", n)
+	}
+
+	expected := `
+# HELP user_profile_db_pool_max_connections Maximum size of the pool.
+# TYPE user_profile_db_pool_max_connections gauge
+user_profile_db_pool_max_connections 7
+# HELP user_profile_db_pool_acquired_connections Connections currently acquired from the pool.
+# TYPE user_profile_db_pool_acquired_connections gauge
+user_profile_db_pool_acquired_connections 0
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"user_profile_db_pool_max_connections", "user_profile_db_pool_acquired_connections")
+	if err != nil {
+		t.Error(err)
+	}
+}
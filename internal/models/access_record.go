@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AccessRecord is an audit record of a read of someone's personal data.
+// Fields lists the JSON names of the profile fields that were returned, never
+// their values.
+type AccessRecord struct {
+	ID         int64     `json:"id"`
+	AccessedAt time.Time `json:"accessed_at"`
+	Caller     string    `json:"caller"`
+	Roles      []string  `json:"roles"`
+	Method     string    `json:"method"`
+	UserID     string    `json:"user_id"`
+	Purpose    string    `json:"purpose"`
+	Fields     []string  `json:"fields"`
+	RequestID  string    `json:"request_id"`
+	// BreakGlassGrantID is set for reads under a break-glass grant.
+	BreakGlassGrantID string `json:"break_glass_grant_id,omitempty"`
+}
+
+// AccessLogFilter selects access records. Zero fields match everything.
+// Records are returned newest first, and BeforeID continues from the last
+// record of a previous page.
+type AccessLogFilter struct {
+	UserID   string
+	Caller   string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64
+	Limit    int
+	// BreakGlass selects only reads under a break-glass grant.
+	BreakGlass bool
+}
+
+// AccessLogPage is one page of access records. NextBeforeID continues the
+// listing as AccessLogFilter.BeforeID, and is zero on the last page.
+type AccessLogPage struct {
+	Records      []AccessRecord
+	NextBeforeID int64
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// BreakGlassGrant lets Subject read every field of one profile until
+// ExpiresAt, e.g. a driving license number during an accident investigation.
+type BreakGlassGrant struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"subject"`
+	UserID    string    `json:"user_id"`
+	Ticket    string    `json:"ticket"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// RevokedAt is set once the grant has been revoked.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type BreakGlassRequest struct {
+	UserID string `json:"user_id"`
+	// Ticket references the incident or support ticket, e.g. "INC-4711".
+	Ticket string `json:"ticket"`
+	Reason string `json:"reason"`
+	// TTL is how long the grant lasts, e.g. "15m". Empty uses the default.
+	TTL string `json:"ttl"`
+}
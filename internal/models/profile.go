@@ -1,6 +1,8 @@
 package models
 
 import (
+	"log/slog"
+	"slices"
 	"time"
 )
 
@@ -23,22 +25,78 @@ type UserProfile struct {
 
 type CreateProfileRequest struct {
 	UserID      string `json:"user_id" validate:"required"`
-	FirstName   string `json:"first_name" validate:"required"`
-	LastName    string `json:"last_name" validate:"required"`
-	Phone       string `json:"phone"`
-	DateOfBirth string `json:"date_of_birth"`
+	FirstName   string `json:"first_name" validate:"required,nocontrol"`
+	LastName    string `json:"last_name" validate:"required,nocontrol"`
+	Phone       string `json:"phone" validate:"nocontrol"`
+	DateOfBirth string `json:"date_of_birth" validate:"nocontrol"`
 }
 
 type UpdateProfileRequest struct {
 	UserID         string `json:"user_id" validate:"required"`
-	FirstName      string `json:"first_name"`
-	LastName       string `json:"last_name"`
-	Phone          string `json:"phone"`
-	DateOfBirth    string `json:"date_of_birth"`
-	AvatarURL      string `json:"avatar_url"`
-	Address        string `json:"address"`
-	City           string `json:"city"`
-	Country        string `json:"country"`
-	PostalCode     string `json:"postal_code"`
-	DrivingLicense string `json:"driving_license"`
+	FirstName      string `json:"first_name" validate:"nocontrol"`
+	LastName       string `json:"last_name" validate:"nocontrol"`
+	Phone          string `json:"phone" validate:"nocontrol"`
+	DateOfBirth    string `json:"date_of_birth" validate:"nocontrol"`
+	AvatarURL      string `json:"avatar_url" validate:"nocontrol"`
+	Address        string `json:"address" validate:"nocontrol"`
+	City           string `json:"city" validate:"nocontrol"`
+	Country        string `json:"country" validate:"nocontrol"`
+	PostalCode     string `json:"postal_code" validate:"nocontrol"`
+	DrivingLicense string `json:"driving_license" validate:"nocontrol"`
+	// Fields lists the JSON names of the fields to change. Listed fields are
+	// set even when empty, which clears them, and the others keep their
+	// stored value. Nil changes every field.
+	Fields []string `json:"-"`
+}
+
+// Updates reports whether the request changes the field with the JSON name.
+func (r *UpdateProfileRequest) Updates(field string) bool {
+	return r.Fields == nil || slices.Contains(r.Fields, field)
+}
+
+// LogValue exposes profile fields under their JSON names so the redacting log
+// handler can mask personal data when a whole profile is logged.
+func (p UserProfile) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", p.ID),
+		slog.String("user_id", p.UserID),
+		slog.String("first_name", p.FirstName),
+		slog.String("last_name", p.LastName),
+		slog.String("phone", p.Phone),
+		slog.String("date_of_birth", p.DateOfBirth),
+		slog.String("avatar_url", p.AvatarURL),
+		slog.String("address", p.Address),
+		slog.String("city", p.City),
+		slog.String("country", p.Country),
+		slog.String("postal_code", p.PostalCode),
+		slog.String("driving_license", p.DrivingLicense),
+		slog.Time("created_at", p.CreatedAt),
+		slog.Time("updated_at", p.UpdatedAt),
+	)
+}
+
+func (r CreateProfileRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("user_id", r.UserID),
+		slog.String("first_name", r.FirstName),
+		slog.String("last_name", r.LastName),
+		slog.String("phone", r.Phone),
+		slog.String("date_of_birth", r.DateOfBirth),
+	)
+}
+
+func (r UpdateProfileRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("user_id", r.UserID),
+		slog.String("first_name", r.FirstName),
+		slog.String("last_name", r.LastName),
+		slog.String("phone", r.Phone),
+		slog.String("date_of_birth", r.DateOfBirth),
+		slog.String("avatar_url", r.AvatarURL),
+		slog.String("address", r.Address),
+		slog.String("city", r.City),
+		slog.String("country", r.Country),
+		slog.String("postal_code", r.PostalCode),
+		slog.String("driving_license", r.DrivingLicense),
+	)
 }
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an unused bucket is kept at least. Buckets that
+// take longer to refill are kept until they are full, since dropping a full
+// bucket changes nothing.
+const idleBucketTTL = 10 * time.Minute
+
+// LocalLimiter keeps buckets in process memory. Each instance enforces its
+// own limits, so the effective limit grows with the number of replicas.
+type LocalLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*localBucket
+	lastSweep time.Time
+}
+
+type localBucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+	// ttl is how long the bucket is kept unused.
+	ttl time.Duration
+}
+
+func NewLocalLimiter() *LocalLimiter {
+	return &LocalLimiter{buckets: map[string]*localBucket{}, lastSweep: time.Now()}
+}
+
+func (l *LocalLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+		b = &localBucket{
+			limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst),
+			ttl:     max(idleBucketTTL, refill),
+		}
+		l.buckets[key] = b
+	}
+	b.lastUsed = now
+	if now.Sub(l.lastSweep) > idleBucketTTL {
+		l.sweep(now)
+	}
+	l.mu.Unlock()
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return Result{RetryAfter: delay}, nil
+	}
+	return Result{Allowed: true}, nil
+}
+
+func (l *LocalLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.lastUsed) > b.ttl {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
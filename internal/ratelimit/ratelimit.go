@@ -0,0 +1,137 @@
+// Package ratelimit enforces token-bucket limits per caller and per target
+// user, with buckets kept in memory or shared through Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wildcard configures the limit of every method without a limit of its own.
+// Those methods share one bucket per key.
+const Wildcard = "*"
+
+// Limit is a token bucket refilled at Rate tokens per second and holding at
+// most Burst tokens. A zero rate means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token. RetryAfter is set when the
+// request was rejected.
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter takes one token from the bucket of key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimits parses "method=rate:burst" entries, e.g. "GetUserProfile=50:100"
+// or "*=20". The burst defaults to the rate rounded up.
+func ParseLimits(entries []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(entries))
+	for _, entry := range entries {
+		method, spec, ok := strings.Cut(entry, "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected method=rate:burst", entry)
+		}
+
+		rateSpec, burstSpec, hasBurst := strings.Cut(spec, ":")
+		rate, err := strconv.ParseFloat(rateSpec, 64)
+		if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("invalid rate in rate limit %q", entry)
+		}
+
+		burst := int(math.Ceil(rate))
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstSpec); err != nil || burst < 1 {
+				return nil, fmt.Errorf("invalid burst in rate limit %q", entry)
+			}
+		}
+
+		limits[method] = Limit{Rate: rate, Burst: max(burst, 1)}
+	}
+	return limits, nil
+}
+
+// Policy applies per-method limits to the caller of a request and to the
+// caller's requests for the user whose profile it targets.
+type Policy struct {
+	limiter Limiter
+	caller  map[string]Limit
+	user    map[string]Limit
+}
+
+func NewPolicy(limiter Limiter, callerLimits, userLimits map[string]Limit) *Policy {
+	return &Policy{
+		limiter: limiter,
+		caller:  callerLimits,
+		user:    userLimits,
+	}
+}
+
+// Rejection describes which limit rejected a request.
+type Rejection struct {
+	// Dimension is "caller" or "user".
+	Dimension  string
+	RetryAfter time.Duration
+}
+
+// Check takes a token from the caller bucket and, when userID is set, from
+// the target user bucket of method. It returns nil when the request may
+// proceed. The user bucket is shared by all callers, so it caps the load on
+// one profile however many callers target it.
+func (p *Policy) Check(ctx context.Context, method, caller, userID string) (*Rejection, error) {
+	if rejection, err := p.take(ctx, p.caller, "caller", method, caller); rejection != nil || err != nil {
+		return rejection, err
+	}
+	if userID == "" {
+		return nil, nil
+	}
+	return p.take(ctx, p.user, "user", method, userID)
+}
+
+func (p *Policy) take(ctx context.Context, limits map[string]Limit, dimension, method, id string) (*Rejection, error) {
+	bucket := method
+	limit, ok := limits[method]
+	if !ok {
+		bucket = Wildcard
+		if limit, ok = limits[Wildcard]; !ok {
+			return nil, nil
+		}
+	}
+	if limit.Rate <= 0 {
+		return nil, nil
+	}
+
+	result, err := p.limiter.Allow(ctx, dimension+":"+bucket+":"+id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if result.Allowed {
+		return nil, nil
+	}
+	return &Rejection{Dimension: dimension, RetryAfter: result.RetryAfter}, nil
+}
+
+// CallerKey identifies the caller of a request: the authenticated subject, or
+// the client address when authentication is disabled.
+func CallerKey(principal *auth.Principal, remoteAddr string) string {
+	if principal != nil {
+		return "sub:" + principal.Subject
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + remoteAddr
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserBucketIsSharedByCallers(t *testing.T) {
+	policy := NewPolicy(NewLocalLimiter(), nil, map[string]Limit{Wildcard: {Rate: 1, Burst: 1}})
+	ctx := context.Background()
+
+	if rejection, err := policy.Check(ctx, "GetUserProfile", "sub:billing", "user-1"); rejection != nil || err != nil {
+		t.Fatalf("first request = %+v, %v, want allowed", rejection, err)
+	}
+	if rejection, _ := policy.Check(ctx, "GetUserProfile", "sub:booking", "user-1"); rejection == nil || rejection.Dimension != "user" {
+		t.Fatalf("request from another caller = %+v, want rejected by the user limit", rejection)
+	}
+
+	// Other profiles have their own bucket
+	if rejection, err := policy.Check(ctx, "GetUserProfile", "sub:booking", "user-2"); rejection != nil || err != nil {
+		t.Errorf("request for another user = %+v, %v, want allowed", rejection, err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits([]string{"*=20", "UpdateUserProfile=0.5:3"})
+	if err != nil {
+		t.Fatalf("ParseLimits: %v", err)
+	}
+	if got := limits[Wildcard]; got != (Limit{Rate: 20, Burst: 20}) {
+		t.Errorf("* = %+v, want rate 20 and burst 20", got)
+	}
+	if got := limits["UpdateUserProfile"]; got != (Limit{Rate: 0.5, Burst: 3}) {
+		t.Errorf("UpdateUserProfile = %+v, want rate 0.5 and burst 3", got)
+	}
+
+	for _, entry := range []string{"GetUserProfile", "=5", "*=-1", "*=5:0", "*=fast"} {
+		if _, err := ParseLimits([]string{entry}); err == nil {
+			t.Errorf("ParseLimits(%q) succeeded", entry)
+		}
+	}
+}
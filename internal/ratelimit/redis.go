@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// tokenBucketScript refills and takes from a bucket stored as a hash. It uses
+// the Redis clock, so instances with skewed clocks share buckets correctly.
+// It returns whether a token was taken and otherwise the milliseconds until
+// one is available.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, retry}
+`)
+
+// RedisLimiter shares buckets between all instances through Redis.
+type RedisLimiter struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisLimiter stores buckets under keyPrefix, e.g. the cache key prefix
+// of the environment.
+func NewRedisLimiter(client redis.UniversalClient, keyPrefix string) *RedisLimiter {
+	return &RedisLimiter{client: client, keyPrefix: keyPrefix}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if l.keyPrefix != "" {
+		key = l.keyPrefix + ":ratelimit:" + key
+	} else {
+		key = "ratelimit:" + key
+	}
+
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(res) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+
+	if res[0] == 1 {
+		return Result{Allowed: true}, nil
+	}
+	return Result{RetryAfter: time.Duration(res[1]) * time.Millisecond}, nil
+}
+
+// FallbackLimiter uses a local limiter while the primary one fails, so a
+// Redis outage neither rejects every request nor lifts all limits.
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	onError  func(error)
+}
+
+func NewFallbackLimiter(primary, fallback Limiter, onError func(error)) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback, onError: onError}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return result, nil
+	}
+	if l.onError != nil {
+		l.onError(err)
+	}
+	return l.fallback.Allow(ctx, key, limit)
+}
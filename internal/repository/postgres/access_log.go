@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppendAccessRecords inserts audit records of reads of personal data. The
+// table rejects updates, so records cannot be changed once written.
+func (r *ProfileRepository) AppendAccessRecords(ctx context.Context, records []models.AccessRecord) error {
+	defer observeQuery("append_access_records", time.Now())
+
+	rows := make([][]any, len(records))
+	for i, rec := range records {
+		// nil slices would be written as NULL
+		roles, fields := rec.Roles, rec.Fields
+		if roles == nil {
+			roles = []string{}
+		}
+		if fields == nil {
+			fields = []string{}
+		}
+		var grantID *string
+		if rec.BreakGlassGrantID != "" {
+			grantID = &rec.BreakGlassGrantID
+		}
+		rows[i] = []any{rec.AccessedAt, rec.Caller, roles, rec.Method, rec.UserID, rec.Purpose, fields, rec.RequestID, grantID}
+	}
+
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"profile_access_log"},
+		[]string{"accessed_at", "caller", "roles", "method", "user_id", "purpose", "fields", "request_id", "break_glass_grant_id"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append access records: %w", err)
+	}
+	return nil
+}
+
+// QueryAccessLog returns the access records matching filter, newest first.
+func (r *ProfileRepository) QueryAccessLog(ctx context.Context, filter models.AccessLogFilter) ([]models.AccessRecord, error) {
+	defer observeQuery("query_access_log", time.Now())
+
+	var conds []string
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.UserID != "" {
+		where("user_id = ?", filter.UserID)
+	}
+	if filter.Caller != "" {
+		where("caller = ?", filter.Caller)
+	}
+	if !filter.Since.IsZero() {
+		where("accessed_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("accessed_at < ?", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		where("id < ?", filter.BeforeID)
+	}
+	if filter.BreakGlass {
+		conds = append(conds, "break_glass_grant_id IS NOT NULL")
+	}
+
+	query := `
+		SELECT id, accessed_at, caller, roles, method, user_id::text, purpose, fields, request_id,
+		       COALESCE(break_glass_grant_id::text, '')
+		FROM profile_access_log`
+	if len(conds) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += "\n\t\tORDER BY id DESC\n\t\tLIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access log: %w", err)
+	}
+
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AccessRecord, error) {
+		var rec models.AccessRecord
+		err := row.Scan(&rec.ID, &rec.AccessedAt, &rec.Caller, &rec.Roles, &rec.Method,
+			&rec.UserID, &rec.Purpose, &rec.Fields, &rec.RequestID, &rec.BreakGlassGrantID)
+		return rec, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access log: %w", err)
+	}
+
+	return records, nil
+}
+
+// PurgeAccessLog deletes up to batchSize access records older than cutoff
+// and returns the number deleted. It is the only way records are removed.
+func (r *ProfileRepository) PurgeAccessLog(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	defer observeQuery("purge_access_log", time.Now())
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SET LOCAL profile_access_log.purge = 'on'"); err != nil {
+		return 0, fmt.Errorf("failed to purge access log: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM profile_access_log
+		WHERE id IN (
+			SELECT id FROM profile_access_log
+			WHERE accessed_at < $1
+			ORDER BY accessed_at
+			LIMIT $2
+		)
+	`, cutoff, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge access log: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit access log purge: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
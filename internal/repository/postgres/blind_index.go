@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"time"
+)
+
+var ErrNoBlindIndex = errors.New("no blind index key configured")
+
+// maxIndexMatches caps the profiles returned by a blind index lookup.
+const maxIndexMatches = 10
+
+// indexedColumn is an encrypted column with a blind index column.
+type indexedColumn struct {
+	column      string
+	indexColumn string
+	normalize   func(string) string
+	value       func(*models.UserProfile) string
+}
+
+var (
+	phoneIndex = indexedColumn{
+		column:      "phone",
+		indexColumn: "phone_index",
+		normalize:   encryption.NormalizePhone,
+		value:       func(p *models.UserProfile) string { return p.Phone },
+	}
+	licenseIndex = indexedColumn{
+		column:      "driving_license",
+		indexColumn: "driving_license_index",
+		normalize:   encryption.NormalizeLicense,
+		value:       func(p *models.UserProfile) string { return p.DrivingLicense },
+	}
+)
+
+// blindIndex returns the index of value for the column, or nil without an
+// index key so that writes leave the index column unchanged.
+func (r *ProfileRepository) blindIndex(c indexedColumn, value string) *string {
+	if r.index == nil {
+		return nil
+	}
+	index := r.index.Compute(c.indexColumn, c.normalize(value))
+	return &index
+}
+
+// FindProfilesByPhone returns the profiles whose phone number matches phone
+// after normalization, most recently updated first.
+func (r *ProfileRepository) FindProfilesByPhone(ctx context.Context, phone string) ([]*models.UserProfile, error) {
+	defer observeQuery("find_profiles_by_phone", time.Now())
+	return r.findByIndex(ctx, phoneIndex, phone)
+}
+
+// FindProfilesByLicense returns the profiles whose driving license number
+// matches license after normalization, most recently updated first.
+func (r *ProfileRepository) FindProfilesByLicense(ctx context.Context, license string) ([]*models.UserProfile, error) {
+	defer observeQuery("find_profiles_by_license", time.Now())
+	return r.findByIndex(ctx, licenseIndex, license)
+}
+
+func (r *ProfileRepository) findByIndex(ctx context.Context, c indexedColumn, value string) ([]*models.UserProfile, error) {
+	if r.index == nil {
+		return nil, ErrNoBlindIndex
+	}
+	normalized := c.normalize(value)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+profileColumns+`
+		FROM user_profiles
+		WHERE %s = $1
+		ORDER BY updated_at DESC
+		LIMIT $2
+	`, c.indexColumn)
+
+	rows, err := r.db.Query(ctx, query, r.index.Compute(c.indexColumn, normalized), maxIndexMatches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find profiles by %s: %w", c.column, err)
+	}
+	defer rows.Close()
+
+	var profiles []*models.UserProfile
+	for rows.Next() {
+		profile, err := r.scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find profiles by %s: %w", c.column, err)
+		}
+		// Guards against stale index values left by an out-of-band update
+		if c.normalize(c.value(profile)) != normalized {
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find profiles by %s: %w", c.column, err)
+	}
+
+	return profiles, nil
+}
+
+// BackfillBlindIndexes computes the blind indexes of rows written before an
+// index key was configured, in batches of batchSize like
+// EncryptPlaintextRows. It returns the number of rows updated.
+func (r *ProfileRepository) BackfillBlindIndexes(ctx context.Context, batchSize int, progress func(done int)) (int, error) {
+	if r.index == nil {
+		return 0, ErrNoBlindIndex
+	}
+
+	done := 0
+	for {
+		n, err := r.backfillBlindIndexBatch(ctx, batchSize)
+		if err != nil {
+			return done, err
+		}
+		done += n
+		if progress != nil && n > 0 {
+			progress(done)
+		}
+		if n < batchSize {
+			return done, nil
+		}
+	}
+}
+
+func (r *ProfileRepository) backfillBlindIndexBatch(ctx context.Context, batchSize int) (int, error) {
+	defer observeQuery("backfill_blind_indexes", time.Now())
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+encryptedRowColumns+`
+		FROM user_profiles
+		WHERE (phone <> '' AND phone_index IS NULL)
+		   OR (driving_license <> '' AND driving_license_index IS NULL)
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select rows without blind indexes: %w", err)
+	}
+	batch, err := pgx.CollectRows(rows, scanEncryptedRow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select rows without blind indexes: %w", err)
+	}
+
+	for _, p := range batch {
+		var indexes [2]*string
+		for i, c := range []indexedColumn{phoneIndex, licenseIndex} {
+			column := slices.Index(encryptedColumns, c.column)
+			stored := p.values[column]
+			if stored == nil {
+				continue
+			}
+			value, err := r.decrypt(c.column, p.userID, *stored, p.encrypted&encryptedBit(column) != 0)
+			if err != nil {
+				return 0, err
+			}
+			indexes[i] = r.blindIndex(c, value)
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE user_profiles
+			SET phone_index = COALESCE($2, phone_index),
+			    driving_license_index = COALESCE($3, driving_license_index)
+			WHERE id = $1
+		`, p.id, indexes[0], indexes[1])
+		if err != nil {
+			return 0, fmt.Errorf("failed to update blind indexes of profile %s: %w", p.id, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit blind indexes: %w", err)
+	}
+
+	return len(batch), nil
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const breakGlassColumns = `id::text, subject, user_id::text, ticket, reason, created_at, expires_at, revoked_at`
+
+func scanBreakGlassGrant(row pgx.Row) (*models.BreakGlassGrant, error) {
+	var g models.BreakGlassGrant
+	err := row.Scan(&g.ID, &g.Subject, &g.UserID, &g.Ticket, &g.Reason, &g.CreatedAt, &g.ExpiresAt, &g.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// CreateBreakGlassGrant stores a grant for subject to read the profile of
+// userID until expiresAt.
+func (r *ProfileRepository) CreateBreakGlassGrant(ctx context.Context, subject string, req *models.BreakGlassRequest, expiresAt time.Time) (*models.BreakGlassGrant, error) {
+	defer observeQuery("create_break_glass_grant", time.Now())
+
+	grant, err := scanBreakGlassGrant(r.db.QueryRow(ctx, `
+		INSERT INTO break_glass_grants (subject, user_id, ticket, reason, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+breakGlassColumns,
+		subject, req.UserID, req.Ticket, req.Reason, expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create break-glass grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// GetBreakGlassGrant returns the grant with id, expired or not, or nil when
+// there is none.
+func (r *ProfileRepository) GetBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	defer observeQuery("get_break_glass_grant", time.Now())
+
+	grant, err := scanBreakGlassGrant(r.db.QueryRow(ctx,
+		"SELECT "+breakGlassColumns+" FROM break_glass_grants WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get break-glass grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// RevokeBreakGlassGrant marks the grant with id as revoked, unless it already
+// is, and returns it. It returns nil when there is no such grant.
+func (r *ProfileRepository) RevokeBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	defer observeQuery("revoke_break_glass_grant", time.Now())
+
+	grant, err := scanBreakGlassGrant(r.db.QueryRow(ctx, `
+		UPDATE break_glass_grants
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING `+breakGlassColumns,
+		id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to revoke break-glass grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// PurgeBreakGlassGrants deletes grants that expired before cutoff and returns
+// the number deleted.
+func (r *ProfileRepository) PurgeBreakGlassGrants(ctx context.Context, cutoff time.Time) (int64, error) {
+	defer observeQuery("purge_break_glass_grants", time.Now())
+
+	tag, err := r.db.Exec(ctx, "DELETE FROM break_glass_grants WHERE expires_at < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge break-glass grants: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
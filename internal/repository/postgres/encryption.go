@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"strings"
+	"time"
+)
+
+// encryptedColumns are the personal data columns stored as encrypted
+// envelopes when a field cipher is configured. Bit i of the encrypted_columns
+// column is set when encryptedColumns[i] holds an envelope.
+var encryptedColumns = []string{"phone", "date_of_birth", "address", "driving_license"}
+
+// encryptedBit returns the encrypted_columns bit of the encryptedColumns
+// entry at index i.
+func encryptedBit(i int) int16 {
+	return 1 << i
+}
+
+// fieldAAD binds a ciphertext to its column and owner, so that values cannot
+// be swapped between columns or rows without failing to decrypt.
+func fieldAAD(column, userID string) string {
+	return "user_profiles." + column + ":" + canonicalUUID(userID)
+}
+
+// canonicalUUID formats a UUID the way Postgres returns it, since the user
+// IDs in requests may use any of the input forms Postgres accepts.
+func canonicalUUID(id string) string {
+	hex := strings.ToLower(strings.NewReplacer("{", "", "}", "", "-", "").Replace(id))
+	if len(hex) != 32 {
+		return id
+	}
+	return hex[0:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:32]
+}
+
+// encryptedFields returns pointers to the profile fields stored in
+// encryptedColumns, in the same order.
+func encryptedFields(p *models.UserProfile) []*string {
+	return []*string{&p.Phone, &p.DateOfBirth, &p.Address, &p.DrivingLicense}
+}
+
+// encrypt returns the value to store in column and whether it is encrypted.
+// Empty values and values written without a cipher are stored as they are.
+func (r *ProfileRepository) encrypt(column, userID, value string) (string, bool, error) {
+	if r.cipher == nil || value == "" {
+		return value, false, nil
+	}
+	encrypted, err := r.cipher.EncryptString(value, fieldAAD(column, userID))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to encrypt %s: %w", column, err)
+	}
+	return encrypted, true, nil
+}
+
+// decryptProfile decrypts the personal data fields of a profile read from the
+// database in place. encrypted is the row's encrypted_columns, so values
+// written before encryption was enabled are left as they are.
+func (r *ProfileRepository) decryptProfile(profile *models.UserProfile, encrypted int16) error {
+	for i, field := range encryptedFields(profile) {
+		value, err := r.decrypt(encryptedColumns[i], profile.UserID, *field, encrypted&encryptedBit(i) != 0)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
+
+func (r *ProfileRepository) decrypt(column, userID, value string, encrypted bool) (string, error) {
+	if !encrypted {
+		return value, nil
+	}
+	if r.cipher == nil {
+		return "", fmt.Errorf("%s is encrypted but no keyring is configured", column)
+	}
+	decrypted, err := r.cipher.DecryptString(value, fieldAAD(column, userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", column, err)
+	}
+	return decrypted, nil
+}
+
+// encryptedRow holds the encryptedColumns of one row as stored, NULL
+// included, and which of them are encrypted.
+type encryptedRow struct {
+	id, userID string
+	values     [4]*string
+	encrypted  int16
+}
+
+// encryptedRowColumns selects an encryptedRow for scanEncryptedRow.
+const encryptedRowColumns = `id::text, user_id::text, phone, date_of_birth, address, driving_license, encrypted_columns`
+
+func scanEncryptedRow(row pgx.CollectableRow) (encryptedRow, error) {
+	var p encryptedRow
+	err := row.Scan(&p.id, &p.userID, &p.values[0], &p.values[1], &p.values[2], &p.values[3], &p.encrypted)
+	return p, err
+}
+
+func updateEncryptedColumns(ctx context.Context, tx pgx.Tx, p encryptedRow) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE user_profiles
+		SET phone = $2, date_of_birth = $3, address = $4, driving_license = $5, encrypted_columns = $6
+		WHERE id = $1
+	`, p.id, p.values[0], p.values[1], p.values[2], p.values[3], p.encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite encrypted columns of profile %s: %w", p.id, err)
+	}
+	return nil
+}
+
+// EncryptPlaintextRows encrypts personal data that was stored before
+// encryption was enabled. Rows are processed in batches of batchSize, each in
+// its own transaction, and locked rows are skipped so the service can keep
+// running. It returns the number of rows rewritten.
+func (r *ProfileRepository) EncryptPlaintextRows(ctx context.Context, batchSize int, progress func(done int)) (int, error) {
+	if r.cipher == nil {
+		return 0, errors.New("no encryption keyring configured")
+	}
+
+	done := 0
+	for {
+		n, err := r.encryptPlaintextBatch(ctx, batchSize)
+		if err != nil {
+			return done, err
+		}
+		done += n
+		if progress != nil && n > 0 {
+			progress(done)
+		}
+		if n < batchSize {
+			return done, nil
+		}
+	}
+}
+
+func (r *ProfileRepository) encryptPlaintextBatch(ctx context.Context, batchSize int) (int, error) {
+	defer observeQuery("encrypt_plaintext_rows", time.Now())
+
+	query := `
+		SELECT ` + encryptedRowColumns + `
+		FROM user_profiles
+		WHERE (phone <> '' AND encrypted_columns & 1 = 0)
+		   OR (date_of_birth <> '' AND encrypted_columns & 2 = 0)
+		   OR (address <> '' AND encrypted_columns & 4 = 0)
+		   OR (driving_license <> '' AND encrypted_columns & 8 = 0)
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select plaintext rows: %w", err)
+	}
+	batch, err := pgx.CollectRows(rows, scanEncryptedRow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select plaintext rows: %w", err)
+	}
+
+	for _, p := range batch {
+		for i, value := range p.values {
+			if value == nil || p.encrypted&encryptedBit(i) != 0 {
+				continue
+			}
+			encrypted, ok, err := r.encrypt(encryptedColumns[i], p.userID, *value)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				p.values[i] = &encrypted
+				p.encrypted |= encryptedBit(i)
+			}
+		}
+
+		if err := updateEncryptedColumns(ctx, tx, p); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit encrypted rows: %w", err)
+	}
+
+	return len(batch), nil
+}
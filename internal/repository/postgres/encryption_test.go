@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"bytes"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"testing"
+)
+
+func TestDecryptProfileUsesEncryptedColumns(t *testing.T) {
+	cipher, err := encryption.NewFieldCipher("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ProfileRepository{cipher: cipher}
+	const userID = "5F0C6A1E-3B2D-4C8E-9F7A-1D2E3F4A5B6C"
+
+	phone, ok, err := r.encrypt("phone", userID, "+48123456789")
+	if err != nil || !ok {
+		t.Fatalf("encrypt = %v, %t, want an encrypted value", err, ok)
+	}
+	if _, ok, _ := r.encrypt("address", userID, ""); ok {
+		t.Error("encrypt marked an empty value as encrypted")
+	}
+
+	// Only the phone is encrypted, the license was written before
+	// encryption was enabled and is read as it is
+	profile := &models.UserProfile{UserID: userID, Phone: phone, DrivingLicense: "PL-12345678"}
+	if err := r.decryptProfile(profile, encryptedBit(0)); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Phone != "+48123456789" || profile.DrivingLicense != "PL-12345678" {
+		t.Errorf("decrypted profile = %+v, want the phone decrypted and the license unchanged", profile)
+	}
+
+	withoutKeyring := &ProfileRepository{}
+	if _, err := withoutKeyring.decrypt("phone", userID, phone, true); err == nil {
+		t.Error("decrypt without a keyring succeeded for an encrypted value")
+	}
+}
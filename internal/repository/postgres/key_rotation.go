@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// KeyRotation is the checkpoint of a job that rewraps every encrypted column
+// with the key KeyID. Rows are visited in primary key order, so a job resumes
+// after LastID.
+type KeyRotation struct {
+	KeyID string
+	// LastID is the last profile ID processed, empty before the first batch.
+	LastID string
+	// Skipped lists profiles that were locked by other transactions when
+	// their batch ran. They are revisited once the table is exhausted.
+	Skipped     []string
+	Scanned     int64
+	Rewrapped   int64
+	Total       int64
+	StartedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+const keyRotationColumns = `key_id, COALESCE(last_id::text, ''), skipped::text[], scanned, rewrapped, total, started_at, updated_at, completed_at`
+
+func scanKeyRotation(row pgx.Row) (*KeyRotation, error) {
+	var k KeyRotation
+	err := row.Scan(&k.KeyID, &k.LastID, &k.Skipped, &k.Scanned, &k.Rewrapped, &k.Total, &k.StartedAt, &k.UpdatedAt, &k.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// StartKeyRotation returns the checkpoint of the rotation to the primary key,
+// creating it if needed. With restart, an existing checkpoint is discarded
+// and the job starts over.
+func (r *ProfileRepository) StartKeyRotation(ctx context.Context, restart bool) (*KeyRotation, error) {
+	defer observeQuery("start_key_rotation", time.Now())
+
+	if r.cipher == nil {
+		return nil, errors.New("no encryption keyring configured")
+	}
+	keyID := r.cipher.PrimaryKeyID()
+
+	if restart {
+		if _, err := r.db.Exec(ctx, "DELETE FROM encryption_key_rotations WHERE key_id = $1", keyID); err != nil {
+			return nil, fmt.Errorf("failed to reset key rotation: %w", err)
+		}
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO encryption_key_rotations (key_id, total)
+		SELECT $1, count(*) FROM user_profiles
+		ON CONFLICT (key_id) DO NOTHING
+	`, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start key rotation: %w", err)
+	}
+
+	rotation, err := scanKeyRotation(r.db.QueryRow(ctx,
+		"SELECT "+keyRotationColumns+" FROM encryption_key_rotations WHERE key_id = $1", keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key rotation: %w", err)
+	}
+
+	return rotation, nil
+}
+
+// ListKeyRotations returns all rotation checkpoints, the most recent first.
+func (r *ProfileRepository) ListKeyRotations(ctx context.Context) ([]*KeyRotation, error) {
+	defer observeQuery("list_key_rotations", time.Now())
+
+	rows, err := r.db.Query(ctx, "SELECT "+keyRotationColumns+" FROM encryption_key_rotations ORDER BY started_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key rotations: %w", err)
+	}
+
+	rotations, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*KeyRotation, error) {
+		return scanKeyRotation(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key rotations: %w", err)
+	}
+
+	return rotations, nil
+}
+
+// RewrapBatch rewraps the encrypted columns of up to batchSize profiles after
+// the checkpoint and advances it in the same transaction, so an interrupted
+// job neither skips nor repeats rows. Profiles locked by other transactions
+// are skipped instead of blocking their writers, recorded on the checkpoint
+// and retried once the table is exhausted. The rotation is updated in place
+// and marked completed when the table is exhausted and no skipped profiles
+// remain.
+func (r *ProfileRepository) RewrapBatch(ctx context.Context, rotation *KeyRotation, batchSize int) error {
+	defer observeQuery("rewrap_batch", time.Now())
+
+	if r.cipher == nil || r.cipher.PrimaryKeyID() != rotation.KeyID {
+		return fmt.Errorf("key rotation targets key %q, which is not the primary key", rotation.KeyID)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lastID *string
+	if rotation.LastID != "" {
+		lastID = &rotation.LastID
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id::text
+		FROM user_profiles
+		WHERE $1::uuid IS NULL OR id > $1::uuid
+		ORDER BY id
+		LIMIT $2
+	`, lastID, batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to select profiles to rewrap: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to select profiles to rewrap: %w", err)
+	}
+	exhausted := len(ids) < batchSize
+	if len(ids) > 0 {
+		lastID = &ids[len(ids)-1]
+	}
+
+	// Skipped profiles are carried forward until the table is exhausted and
+	// then retried, unless they were deleted since
+	carried := rotation.Skipped
+	if exhausted && len(carried) > 0 {
+		rows, err := tx.Query(ctx, "SELECT id::text FROM user_profiles WHERE id = ANY($1::text[]::uuid[])", carried)
+		if err != nil {
+			return fmt.Errorf("failed to select skipped profiles: %w", err)
+		}
+		existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to select skipped profiles: %w", err)
+		}
+		ids = append(ids, existing...)
+		carried = nil
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+encryptedRowColumns+`
+		FROM user_profiles
+		WHERE id = ANY($1::text[]::uuid[])
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to select profiles to rewrap: %w", err)
+	}
+	batch, err := pgx.CollectRows(rows, scanEncryptedRow)
+	if err != nil {
+		return fmt.Errorf("failed to select profiles to rewrap: %w", err)
+	}
+	skipped := append(skippedIDs(ids, batch), carried...)
+
+	rewrapped := 0
+	for _, p := range batch {
+		changed := false
+		for i, value := range p.values {
+			if value == nil || p.encrypted&encryptedBit(i) == 0 {
+				continue
+			}
+			v, ok, err := r.cipher.RewrapString(*value)
+			if err != nil {
+				return fmt.Errorf("failed to rewrap %s of profile %s: %w", encryptedColumns[i], p.id, err)
+			}
+			if ok {
+				p.values[i] = &v
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := updateEncryptedColumns(ctx, tx, p); err != nil {
+			return err
+		}
+		rewrapped++
+	}
+
+	updated, err := scanKeyRotation(tx.QueryRow(ctx, `
+		UPDATE encryption_key_rotations
+		SET last_id = $2::uuid,
+		    skipped = $6::text[]::uuid[],
+		    scanned = scanned + $3,
+		    rewrapped = rewrapped + $4,
+		    updated_at = NOW(),
+		    completed_at = CASE WHEN $5::boolean THEN NOW() END
+		WHERE key_id = $1
+		RETURNING `+keyRotationColumns,
+		rotation.KeyID, lastID, len(batch), rewrapped, exhausted && len(skipped) == 0, skipped))
+	if err != nil {
+		return fmt.Errorf("failed to update key rotation checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rewrapped profiles: %w", err)
+	}
+
+	*rotation = *updated
+	return nil
+}
+
+// skippedIDs returns the IDs missing from the locked batch. It is never nil,
+// since the checkpoint column is not nullable.
+func skippedIDs(ids []string, batch []encryptedRow) []string {
+	locked := make(map[string]bool, len(batch))
+	for _, p := range batch {
+		locked[p.id] = true
+	}
+
+	skipped := []string{}
+	for _, id := range ids {
+		if !locked[id] {
+			skipped = append(skipped, id)
+		}
+	}
+	return skipped
+}
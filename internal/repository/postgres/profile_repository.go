@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
 	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"time"
@@ -12,13 +15,19 @@ import (
 
 type ProfileRepository struct {
 	db *pgxpool.Pool
+	// cipher encrypts personal data columns. Nil stores them in plaintext.
+	cipher *encryption.FieldCipher
+	// index maintains the blind index columns. Nil leaves them empty and
+	// disables lookups by phone and license.
+	index *encryption.BlindIndex
 }
 
-func NewProfileRepository(databaseURL string) (*ProfileRepository, error) {
+func NewProfileRepository(databaseURL string, cipher *encryption.FieldCipher, index *encryption.BlindIndex) (*ProfileRepository, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
+	config.ConnConfig.Tracer = otelpgx.NewTracer()
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -30,7 +39,11 @@ func NewProfileRepository(databaseURL string) (*ProfileRepository, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &ProfileRepository{db: pool}, nil
+	return &ProfileRepository{db: pool, cipher: cipher, index: index}, nil
+}
+
+func observeQuery(query string, start time.Time) {
+	metrics.DBQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
 }
 
 func (r *ProfileRepository) Close() {
@@ -40,21 +53,41 @@ func (r *ProfileRepository) Close() {
 }
 
 func (r *ProfileRepository) CreateProfile(ctx context.Context, profile *models.CreateProfileRequest) (*models.UserProfile, error) {
+	defer observeQuery("create_profile", time.Now())
+
 	query := `
-		INSERT INTO user_profiles (user_id, first_name, last_name, phone, date_of_birth)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO user_profiles (user_id, first_name, last_name, phone, date_of_birth, phone_index, encrypted_columns)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at
 	`
 
+	var encrypted int16
+	phone, ok, err := r.encrypt("phone", profile.UserID, profile.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		encrypted |= encryptedBit(0)
+	}
+	dateOfBirth, ok, err := r.encrypt("date_of_birth", profile.UserID, profile.DateOfBirth)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		encrypted |= encryptedBit(1)
+	}
+
 	var id string
 	var createdAt, updatedAt time.Time
 
-	err := r.db.QueryRow(ctx, query,
+	err = r.db.QueryRow(ctx, query,
 		profile.UserID,
 		profile.FirstName,
 		profile.LastName,
-		profile.Phone,
-		profile.DateOfBirth,
+		phone,
+		dateOfBirth,
+		r.blindIndex(phoneIndex, profile.Phone),
+		encrypted,
 	).Scan(&id, &createdAt, &updatedAt)
 
 	if err != nil {
@@ -74,32 +107,15 @@ func (r *ProfileRepository) CreateProfile(ctx context.Context, profile *models.C
 }
 
 func (r *ProfileRepository) GetProfileByID(ctx context.Context, id string) (*models.UserProfile, error) {
+	defer observeQuery("get_profile_by_id", time.Now())
+
 	query := `
-		SELECT id, user_id, first_name, last_name, phone, date_of_birth, 
-		       avatar_url, address, city, country, postal_code, driving_license,
-		       created_at, updated_at
+		SELECT ` + profileColumns + `
 		FROM user_profiles
 		WHERE id = $1
 	`
 
-	var profile models.UserProfile
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&profile.ID,
-		&profile.UserID,
-		&profile.FirstName,
-		&profile.LastName,
-		&profile.Phone,
-		&profile.DateOfBirth,
-		&profile.AvatarURL,
-		&profile.Address,
-		&profile.City,
-		&profile.Country,
-		&profile.PostalCode,
-		&profile.DrivingLicense,
-		&profile.CreatedAt,
-		&profile.UpdatedAt,
-	)
-
+	profile, err := r.scanProfile(r.db.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -107,36 +123,19 @@ func (r *ProfileRepository) GetProfileByID(ctx context.Context, id string) (*mod
 		return nil, fmt.Errorf("failed to get profile by ID: %w", err)
 	}
 
-	return &profile, nil
+	return profile, nil
 }
 
 func (r *ProfileRepository) GetProfileByUserID(ctx context.Context, userID string) (*models.UserProfile, error) {
+	defer observeQuery("get_profile_by_user_id", time.Now())
+
 	query := `
-		SELECT id, user_id, first_name, last_name, phone, date_of_birth, 
-		       avatar_url, address, city, country, postal_code, driving_license,
-		       created_at, updated_at
+		SELECT ` + profileColumns + `
 		FROM user_profiles
 		WHERE user_id = $1
 	`
 
-	var profile models.UserProfile
-	err := r.db.QueryRow(ctx, query, userID).Scan(
-		&profile.ID,
-		&profile.UserID,
-		&profile.FirstName,
-		&profile.LastName,
-		&profile.Phone,
-		&profile.DateOfBirth,
-		&profile.AvatarURL,
-		&profile.Address,
-		&profile.City,
-		&profile.Country,
-		&profile.PostalCode,
-		&profile.DrivingLicense,
-		&profile.CreatedAt,
-		&profile.UpdatedAt,
-	)
-
+	profile, err := r.scanProfile(r.db.QueryRow(ctx, query, userID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -144,10 +143,12 @@ func (r *ProfileRepository) GetProfileByUserID(ctx context.Context, userID strin
 		return nil, fmt.Errorf("failed to get profile by user ID: %w", err)
 	}
 
-	return &profile, nil
+	return profile, nil
 }
 
 func (r *ProfileRepository) UpdateProfile(ctx context.Context, userID string, updates *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	defer observeQuery("update_profile", time.Now())
+
 	query := `
 		UPDATE user_profiles 
 		SET first_name = COALESCE($1, first_name),
@@ -160,54 +161,132 @@ func (r *ProfileRepository) UpdateProfile(ctx context.Context, userID string, up
 		    country = COALESCE($8, country),
 		    postal_code = COALESCE($9, postal_code),
 		    driving_license = COALESCE($10, driving_license),
+		    phone_index = COALESCE($12, phone_index),
+		    driving_license_index = COALESCE($13, driving_license_index),
+		    encrypted_columns = (encrypted_columns & ~$14::smallint) | $15::smallint,
 		    updated_at = NOW()
 		WHERE user_id = $11
-		RETURNING id, user_id, first_name, last_name, phone, date_of_birth, 
-		          avatar_url, address, city, country, postal_code, driving_license,
-		          created_at, updated_at
+		RETURNING ` + profileColumns + `
 	`
 
-	var profile models.UserProfile
-	err := r.db.QueryRow(ctx, query,
-		updates.FirstName,
-		updates.LastName,
-		updates.Phone,
-		updates.DateOfBirth,
-		updates.AvatarURL,
-		updates.Address,
-		updates.City,
-		updates.Country,
-		updates.PostalCode,
-		updates.DrivingLicense,
+	// Fields outside the request's field mask are bound as NULL so that
+	// COALESCE keeps the stored value
+	values := make(map[string]*string)
+	for field, value := range map[string]string{
+		"first_name":  updates.FirstName,
+		"last_name":   updates.LastName,
+		"avatar_url":  updates.AvatarURL,
+		"city":        updates.City,
+		"country":     updates.Country,
+		"postal_code": updates.PostalCode,
+	} {
+		if updates.Updates(field) {
+			values[field] = &value
+		}
+	}
+	// written and encrypted are the encrypted_columns bits to clear and set
+	var written, encrypted int16
+	for i, value := range []string{updates.Phone, updates.DateOfBirth, updates.Address, updates.DrivingLicense} {
+		if !updates.Updates(encryptedColumns[i]) {
+			continue
+		}
+		stored, ok, err := r.encrypt(encryptedColumns[i], userID, value)
+		if err != nil {
+			return nil, err
+		}
+		values[encryptedColumns[i]] = &stored
+		written |= encryptedBit(i)
+		if ok {
+			encrypted |= encryptedBit(i)
+		}
+	}
+
+	var phoneIndexValue, licenseIndexValue *string
+	if updates.Updates(phoneIndex.column) {
+		phoneIndexValue = r.blindIndex(phoneIndex, updates.Phone)
+	}
+	if updates.Updates(licenseIndex.column) {
+		licenseIndexValue = r.blindIndex(licenseIndex, updates.DrivingLicense)
+	}
+
+	profile, err := r.scanProfile(r.db.QueryRow(ctx, query,
+		values["first_name"],
+		values["last_name"],
+		values["phone"],
+		values["date_of_birth"],
+		values["avatar_url"],
+		values["address"],
+		values["city"],
+		values["country"],
+		values["postal_code"],
+		values["driving_license"],
 		userID,
-	).Scan(
+		phoneIndexValue,
+		licenseIndexValue,
+		written,
+		encrypted,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// profileColumns selects a whole profile for scanProfile.
+const profileColumns = `id, user_id, first_name, last_name, phone, date_of_birth,
+		       avatar_url, address, city, country, postal_code, driving_license,
+		       created_at, updated_at, encrypted_columns`
+
+// scanProfile scans a row selected with profileColumns and decrypts it. The
+// optional columns are nullable and read as empty strings.
+func (r *ProfileRepository) scanProfile(row pgx.Row) (*models.UserProfile, error) {
+	var profile models.UserProfile
+	var optional [8]*string
+	var encrypted int16
+	err := row.Scan(
 		&profile.ID,
 		&profile.UserID,
 		&profile.FirstName,
 		&profile.LastName,
-		&profile.Phone,
-		&profile.DateOfBirth,
-		&profile.AvatarURL,
-		&profile.Address,
-		&profile.City,
-		&profile.Country,
-		&profile.PostalCode,
-		&profile.DrivingLicense,
+		&optional[0],
+		&optional[1],
+		&optional[2],
+		&optional[3],
+		&optional[4],
+		&optional[5],
+		&optional[6],
+		&optional[7],
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
+		&encrypted,
 	)
-
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+		return nil, err
+	}
+
+	fields := []*string{
+		&profile.Phone, &profile.DateOfBirth, &profile.AvatarURL, &profile.Address,
+		&profile.City, &profile.Country, &profile.PostalCode, &profile.DrivingLicense,
+	}
+	for i, value := range optional {
+		if value != nil {
+			*fields[i] = *value
 		}
-		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
+	if err := r.decryptProfile(&profile, encrypted); err != nil {
+		return nil, err
+	}
 	return &profile, nil
 }
 
 func (r *ProfileRepository) DeleteProfile(ctx context.Context, userID string) error {
+	defer observeQuery("delete_profile", time.Now())
+
 	query := "DELETE FROM user_profiles WHERE user_id = $1"
 	result, err := r.db.Exec(ctx, query, userID)
 	if err != nil {
@@ -215,8 +294,95 @@ func (r *ProfileRepository) DeleteProfile(ctx context.Context, userID string) er
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("profile not found for user ID: %s", userID)
+		return errors.New("profile not found")
 	}
 
 	return nil
 }
+
+// SampleUserIDs returns up to n user IDs starting from a random point of the
+// primary key space. It is cheap enough to run periodically on large tables.
+func (r *ProfileRepository) SampleUserIDs(ctx context.Context, n int) ([]string, error) {
+	defer observeQuery("sample_user_ids", time.Now())
+
+	// Both halves split the table at the same random ID, so they never
+	// return the same row twice
+	query := `
+		WITH start AS (SELECT gen_random_uuid() AS id)
+		SELECT user_id FROM (
+			(SELECT 0 AS part, p.id, p.user_id FROM user_profiles p, start WHERE p.id >= start.id ORDER BY p.id LIMIT $1)
+			UNION ALL
+			(SELECT 1 AS part, p.id, p.user_id FROM user_profiles p, start WHERE p.id < start.id ORDER BY p.id LIMIT $1)
+		) AS sample
+		ORDER BY part, id
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample user IDs: %w", err)
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample user IDs: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// ProfileCursor marks the last row of a page returned by ListRecentlyUpdated.
+type ProfileCursor struct {
+	UpdatedAt time.Time
+	UserID    string
+}
+
+// ListRecentlyUpdated returns up to limit profiles ordered from the most
+// recently updated, starting after the given cursor (nil for the first page).
+func (r *ProfileRepository) ListRecentlyUpdated(ctx context.Context, after *ProfileCursor, limit int) ([]*models.UserProfile, error) {
+	defer observeQuery("list_recently_updated", time.Now())
+
+	query := `
+		SELECT ` + profileColumns + `
+		FROM user_profiles
+		WHERE $1::timestamptz IS NULL OR (updated_at, user_id) < ($1, $2::uuid)
+		ORDER BY updated_at DESC, user_id DESC
+		LIMIT $3
+	`
+
+	var afterUpdatedAt *time.Time
+	var afterUserID *string
+	if after != nil {
+		afterUpdatedAt = &after.UpdatedAt
+		afterUserID = &after.UserID
+	}
+
+	rows, err := r.db.Query(ctx, query, afterUpdatedAt, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recently updated profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := make([]*models.UserProfile, 0, limit)
+	for rows.Next() {
+		profile, err := r.scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list recently updated profiles: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list recently updated profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+func (r *ProfileRepository) Stat() *pgxpool.Stat {
+	return r.db.Stat()
+}
+
+func (r *ProfileRepository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
@@ -2,34 +2,115 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
 	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"math/rand/v2"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrNegativeHit is returned by GetCachedProfile when the profile is cached as
+// missing, so callers can skip the database lookup.
+var ErrNegativeHit = errors.New("profile cached as not found")
+
+// ErrCorruptEntry is returned by GetCachedProfile when a cached value has a
+// valid envelope but cannot be decoded.
+var ErrCorruptEntry = errors.New("corrupt cache entry")
+
+// CacheOptions controls how long entries live and how keys are named.
+type CacheOptions struct {
+	// TTL applies to profiles cached one at a time.
+	TTL time.Duration
+	// NegativeTTL applies to "profile not found" entries. Zero disables negative caching.
+	NegativeTTL time.Duration
+	// BatchTTL applies to profiles written by CacheProfileList.
+	BatchTTL time.Duration
+	// Jitter adds a random duration in [0, Jitter) to the TTL and BatchTTL of
+	// cached profiles to avoid synchronized expiry. Negative entries are short
+	// lived and keep their exact NegativeTTL.
+	Jitter time.Duration
+	// KeyPrefix namespaces keys, e.g. per environment.
+	KeyPrefix string
+	// Codec selects the payload encoding.
+	Codec Codec
+	// Compress deflates payloads before they are stored.
+	Compress bool
+	// Cipher encrypts payloads before they are stored. Nil stores them in
+	// plaintext.
+	Cipher *encryption.FieldCipher
+}
+
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		TTL:         1 * time.Hour,
+		NegativeTTL: 1 * time.Minute,
+		BatchTTL:    1 * time.Hour,
+		Jitter:      5 * time.Minute,
+		Codec:       CodecMsgpack,
+	}
+}
+
+func (o CacheOptions) Validate() error {
+	if o.TTL <= 0 {
+		return fmt.Errorf("cache TTL must be positive, got %s", o.TTL)
+	}
+	if o.BatchTTL <= 0 {
+		return fmt.Errorf("cache batch TTL must be positive, got %s", o.BatchTTL)
+	}
+	if o.NegativeTTL < 0 {
+		return fmt.Errorf("cache negative TTL must not be negative, got %s", o.NegativeTTL)
+	}
+	if o.Jitter < 0 {
+		return fmt.Errorf("cache TTL jitter must not be negative, got %s", o.Jitter)
+	}
+	if o.Jitter > min(o.TTL, o.BatchTTL)/2 {
+		return fmt.Errorf("cache TTL jitter %s must be at most half of the TTLs it is applied to", o.Jitter)
+	}
+	if strings.ContainsAny(o.KeyPrefix, " \t\r\n") {
+		return fmt.Errorf("cache key prefix must not contain whitespace: %q", o.KeyPrefix)
+	}
+	if o.Codec != CodecJSON && o.Codec != CodecMsgpack {
+		return fmt.Errorf("unsupported cache codec %s", o.Codec)
+	}
+	return nil
+}
+
 type CacheRepository struct {
-	client *redis.Client
-	ttl    time.Duration
+	client redis.UniversalClient
+	opts   CacheOptions
 }
 
-func NewCacheRepository(redisURL string) (*CacheRepository, error) {
-	opts, err := redis.ParseURL(redisURL)
+func NewCacheRepository(clientCfg ClientConfig, cacheOpts CacheOptions) (*CacheRepository, error) {
+	if err := cacheOpts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cache options: %w", err)
+	}
+
+	client, err := newClient(clientCfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		return nil, err
 	}
 
-	client := redis.NewClient(opts)
+	// Trace Redis commands with the global tracer provider
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to instrument Redis tracing: %w", err)
+	}
 
 	// Test connection
-	if err := client.Ping(context.Background()).Err(); err != nil {
+	if err := pingClient(context.Background(), client); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	return &CacheRepository{
 		client: client,
-		ttl:    1 * time.Hour, // default TTL
+		opts:   cacheOpts,
 	}, nil
 }
 
@@ -39,73 +120,256 @@ func (r *CacheRepository) Close() {
 	}
 }
 
-func (r *CacheRepository) SetTTL(ttl time.Duration) {
-	r.ttl = ttl
+// Client returns the underlying Redis client, so other components such as
+// the rate limiter can share its connections.
+func (r *CacheRepository) Client() redis.UniversalClient {
+	return r.client
 }
 
-func (r *CacheRepository) CacheProfile(ctx context.Context, profile *models.UserProfile) error {
-	key := fmt.Sprintf("user_profile:%s", profile.UserID)
+func (r *CacheRepository) Ping(ctx context.Context) error {
+	return pingClient(ctx, r.client)
+}
 
-	profileJSON, err := json.Marshal(profile)
+// SetTTL changes the TTL of profiles cached one at a time. It must be called
+// before the repository is used, and ttl must pass the same checks as
+// CacheOptions.TTL.
+func (r *CacheRepository) SetTTL(ttl time.Duration) error {
+	opts := r.opts
+	opts.TTL = ttl
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid cache TTL: %w", err)
+	}
+	r.opts = opts
+	return nil
+}
+
+func (r *CacheRepository) key(userID string) string {
+	if r.opts.KeyPrefix == "" {
+		return fmt.Sprintf("user_profile:%s", userID)
+	}
+	return fmt.Sprintf("%s:user_profile:%s", r.opts.KeyPrefix, userID)
+}
+
+func (r *CacheRepository) withJitter(ttl time.Duration) time.Duration {
+	if r.opts.Jitter <= 0 {
+		return ttl
+	}
+	return ttl + rand.N(r.opts.Jitter)
+}
+
+func (r *CacheRepository) CacheProfile(ctx context.Context, profile *models.UserProfile) error {
+	key := r.key(profile.UserID)
+	payload, err := encodeProfile(profile, r.opts.Codec, r.opts.Compress, r.opts.Cipher, []byte(key))
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile: %w", err)
 	}
 
-	err = r.client.Set(ctx, key, profileJSON, r.ttl).Err()
+	err = r.client.Set(ctx, key, payload, r.withJitter(r.opts.TTL)).Err()
 	if err != nil {
+		metrics.CacheErrors.WithLabelValues("set").Inc()
 		return fmt.Errorf("failed to cache profile: %w", err)
 	}
 
 	return nil
 }
 
-func (r *CacheRepository) GetCachedProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
-	key := fmt.Sprintf("user_profile:%s", userID)
+// CacheNotFound records that no profile exists for userID. It is a no-op when
+// negative caching is disabled.
+func (r *CacheRepository) CacheNotFound(ctx context.Context, userID string) error {
+	if r.opts.NegativeTTL <= 0 {
+		return nil
+	}
+
+	err := r.client.Set(ctx, r.key(userID), encodeNotFound(), r.opts.NegativeTTL).Err()
+	if err != nil {
+		metrics.CacheErrors.WithLabelValues("set_not_found").Inc()
+		return fmt.Errorf("failed to cache missing profile: %w", err)
+	}
 
-	profileJSON, err := r.client.Get(ctx, key).Result()
+	return nil
+}
+
+func (r *CacheRepository) GetCachedProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	key := r.key(userID)
+	payload, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			metrics.CacheMisses.Inc()
 			return nil, nil
 		}
+		metrics.CacheErrors.WithLabelValues("get").Inc()
 		return nil, fmt.Errorf("failed to get cached profile: %w", err)
 	}
 
-	var profile models.UserProfile
-	err = json.Unmarshal([]byte(profileJSON), &profile)
+	profile, err := decodeProfile(payload, r.opts.Cipher, []byte(key))
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal profile: %w", err)
+		if errors.Is(err, errStaleEntry) {
+			// Written by a build with another schema version or encryption setting
+			metrics.CacheMisses.Inc()
+			return nil, nil
+		}
+		if errors.Is(err, ErrNegativeHit) {
+			metrics.CacheHits.Inc()
+			return nil, err
+		}
+		if errors.Is(err, encryption.ErrDecrypt) {
+			// Encrypted with a key that is unknown or has been retired
+			metrics.CacheMisses.Inc()
+			if err := r.client.Del(ctx, key).Err(); err != nil {
+				metrics.CacheErrors.WithLabelValues("delete").Inc()
+			}
+			return nil, nil
+		}
+		metrics.CacheErrors.WithLabelValues("decode").Inc()
+		return nil, fmt.Errorf("failed to unmarshal profile: %w: %w", ErrCorruptEntry, err)
 	}
 
-	return &profile, nil
+	metrics.CacheHits.Inc()
+	return profile, nil
 }
 
 func (r *CacheRepository) DeleteCachedProfile(ctx context.Context, userID string) error {
-	key := fmt.Sprintf("user_profile:%s", userID)
-	err := r.client.Del(ctx, key).Err()
+	err := r.client.Del(ctx, r.key(userID)).Err()
 	if err != nil {
+		metrics.CacheErrors.WithLabelValues("delete").Inc()
 		return fmt.Errorf("failed to delete cached profile: %w", err)
 	}
 	return nil
 }
 
-func (r *CacheRepository) CacheProfileList(ctx context.Context, userIDs []string, profiles []*models.UserProfile) error {
-	pipeline := r.client.Pipeline()
+// replaceScript replaces a cached value only if it is still the one read
+// before (ARGV[1] = "1" when the key was missing, ARGV[2] otherwise), so a
+// newer profile cached by a request in the meantime is kept. It returns 1
+// when the value was written.
+var replaceScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if (ARGV[1] == "1" and not current) or current == ARGV[2] then
+	redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+	return 1
+end
+return 0
+`)
 
+// CacheProfileList caches profiles read from the database. Existing entries
+// are replaced when they hold an older version of the profile or cannot be
+// decoded, e.g. because they were written by a build with another schema
+// version or under a retired key. Entries that changed since they were
+// checked, and negative entries, are left alone.
+func (r *CacheRepository) CacheProfileList(ctx context.Context, userIDs []string, profiles []*models.UserProfile) error {
+	keys := make([]string, 0, len(userIDs))
+	batch := make([]*models.UserProfile, 0, len(userIDs))
 	for i, userID := range userIDs {
 		if i < len(profiles) && profiles[i] != nil {
-			key := fmt.Sprintf("user_profile:%s", userID)
-			profileJSON, err := json.Marshal(profiles[i])
-			if err != nil {
-				return fmt.Errorf("failed to marshal profile: %w", err)
-			}
-			pipeline.Set(ctx, key, profileJSON, r.ttl)
+			keys = append(keys, r.key(userID))
+			batch = append(batch, profiles[i])
 		}
 	}
+	if len(keys) == 0 {
+		return nil
+	}
 
-	_, err := pipeline.Exec(ctx)
-	if err != nil {
+	// Plain (non-transactional) pipelines are used on purpose: the cluster
+	// client splits them per hash slot, while MULTI would require all keys to
+	// share a slot.
+	pipeline := r.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipeline.Get(ctx, key)
+	}
+	if _, err := pipeline.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		metrics.CacheErrors.WithLabelValues("get_batch").Inc()
+		return fmt.Errorf("failed to read cached profile list: %w", err)
+	}
+
+	pipeline = r.client.Pipeline()
+	for i, key := range keys {
+		current, err := gets[i].Bytes()
+		missing := errors.Is(err, redis.Nil)
+		if err != nil && !missing {
+			metrics.CacheErrors.WithLabelValues("get_batch").Inc()
+			return fmt.Errorf("failed to read cached profile list: %w", err)
+		}
+		if !missing && !r.outdated(current, key, batch[i]) {
+			continue
+		}
+
+		payload, err := encodeProfile(batch[i], r.opts.Codec, r.opts.Compress, r.opts.Cipher, []byte(key))
+		if err != nil {
+			return fmt.Errorf("failed to marshal profile: %w", err)
+		}
+		wasMissing := "0"
+		if missing {
+			wasMissing = "1"
+		}
+		replaceScript.Eval(ctx, pipeline, []string{key},
+			wasMissing, current, payload, r.withJitter(r.opts.BatchTTL).Milliseconds())
+	}
+
+	if _, err := pipeline.Exec(ctx); err != nil {
+		metrics.CacheErrors.WithLabelValues("set_batch").Inc()
 		return fmt.Errorf("failed to cache profile list: %w", err)
 	}
 
 	return nil
 }
+
+// outdated reports whether the cached value under key should be replaced by
+// profile.
+func (r *CacheRepository) outdated(current []byte, key string, profile *models.UserProfile) bool {
+	cached, err := decodeProfile(current, r.opts.Cipher, []byte(key))
+	if errors.Is(err, ErrNegativeHit) {
+		// Short lived, and possibly written after the profile was deleted
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return cached.UpdatedAt.Before(profile.UpdatedAt)
+}
+
+// ScanUserIDs walks all cached profile keys in batches of roughly batchSize
+// and passes the user IDs to fn. In cluster mode every master is scanned.
+func (r *CacheRepository) ScanUserIDs(ctx context.Context, batchSize int, fn func(userIDs []string) error) error {
+	pattern := r.key("*")
+	prefix := strings.TrimSuffix(pattern, "*")
+
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, int64(batchSize)).Result()
+			if err != nil {
+				return fmt.Errorf("failed to scan cached profiles: %w", err)
+			}
+
+			if len(keys) > 0 {
+				userIDs := make([]string, 0, len(keys))
+				for _, key := range keys {
+					userIDs = append(userIDs, strings.TrimPrefix(key, prefix))
+				}
+				if err := fn(userIDs); err != nil {
+					return err
+				}
+			}
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		// Masters are scanned concurrently, but fn is called one batch at a time
+		var mu sync.Mutex
+		serialized := fn
+		fn = func(userIDs []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return serialized(userIDs)
+		}
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return scan(ctx, master)
+		})
+	}
+	return scan(ctx, r.client)
+}
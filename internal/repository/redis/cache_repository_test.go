@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetTTLValidates(t *testing.T) {
+	r := &CacheRepository{opts: DefaultCacheOptions()}
+
+	// The default jitter is 5m, so the TTL must be at least 10m
+	for _, ttl := range []time.Duration{0, -time.Minute, 9 * time.Minute} {
+		if err := r.SetTTL(ttl); err == nil {
+			t.Errorf("SetTTL(%s) succeeded", ttl)
+		}
+	}
+	if r.opts.TTL != time.Hour {
+		t.Errorf("TTL = %s after rejected changes, want it unchanged at 1h", r.opts.TTL)
+	}
+
+	if err := r.SetTTL(30 * time.Minute); err != nil || r.opts.TTL != 30*time.Minute {
+		t.Errorf("SetTTL(30m) = %v with TTL %s, want 30m", err, r.opts.TTL)
+	}
+}
+
+func TestOutdatedEntries(t *testing.T) {
+	r := &CacheRepository{opts: DefaultCacheOptions()}
+	key := r.key("b2a4c6d8-1e3f-4a5b-8c7d-9e0f1a2b3c4d")
+	profile := benchmarkProfile()
+
+	encode := func(updatedAt time.Time) []byte {
+		cached := *profile
+		cached.UpdatedAt = updatedAt
+		payload, err := encodeProfile(&cached, CodecMsgpack, false, nil, []byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+	otherSchema := encode(profile.UpdatedAt)
+	otherSchema[1] = SchemaVersion + 1
+
+	tests := []struct {
+		name     string
+		current  []byte
+		outdated bool
+	}{
+		{"older", encode(profile.UpdatedAt.Add(-time.Minute)), true},
+		{"same", encode(profile.UpdatedAt), false},
+		{"newer", encode(profile.UpdatedAt.Add(time.Minute)), false},
+		{"other schema version", otherSchema, true},
+		{"undecodable", []byte("garbage"), true},
+		{"not found", encodeNotFound(), false},
+	}
+	for _, tt := range tests {
+		if got := r.outdated(tt.current, key, profile); got != tt.outdated {
+			t.Errorf("%s entry outdated = %t, want %t", tt.name, got, tt.outdated)
+		}
+	}
+}
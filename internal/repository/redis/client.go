@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"os"
+)
+
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// ClientConfig describes how to reach Redis. Structured fields override the
+// corresponding values parsed from URL when set.
+type ClientConfig struct {
+	Mode string
+	// URL is a redis:// or rediss:// URL. For sentinel it must carry a
+	// master_name query parameter, for cluster extra seed nodes can be given
+	// with repeated addr parameters.
+	URL string
+	// Addrs lists host:port seeds and takes precedence over URL.
+	Addrs      []string
+	MasterName string
+
+	Username         string
+	Password         string
+	SentinelUsername string
+	SentinelPassword string
+	DB               int
+
+	TLS                   bool
+	TLSCAFile             string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+
+	// ReadFromReplica routes read-only commands to replicas in sentinel and
+	// cluster modes. Writes always go to the master.
+	ReadFromReplica bool
+}
+
+func newClient(cfg ClientConfig) (redis.UniversalClient, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Mode {
+	case "", ModeStandalone:
+		opts := &redis.Options{}
+		if len(cfg.Addrs) > 1 {
+			return nil, fmt.Errorf("Redis standalone mode takes a single address, got %d; use sentinel or cluster mode for several nodes", len(cfg.Addrs))
+		}
+		if len(cfg.Addrs) == 1 {
+			opts.Addr = cfg.Addrs[0]
+		} else if opts, err = redis.ParseURL(cfg.URL); err != nil {
+			return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		}
+		setString(&opts.Username, cfg.Username)
+		setString(&opts.Password, cfg.Password)
+		if cfg.DB != 0 {
+			opts.DB = cfg.DB
+		}
+		if tlsConfig != nil {
+			opts.TLSConfig = tlsConfig
+		}
+		return redis.NewClient(opts), nil
+
+	case ModeSentinel:
+		opts := &redis.FailoverOptions{}
+		if len(cfg.Addrs) > 0 {
+			opts.SentinelAddrs = cfg.Addrs
+		} else if opts, err = redis.ParseFailoverURL(cfg.URL); err != nil {
+			return nil, fmt.Errorf("failed to parse Redis sentinel URL: %w", err)
+		}
+		setString(&opts.MasterName, cfg.MasterName)
+		setString(&opts.Username, cfg.Username)
+		setString(&opts.Password, cfg.Password)
+		setString(&opts.SentinelUsername, cfg.SentinelUsername)
+		setString(&opts.SentinelPassword, cfg.SentinelPassword)
+		if cfg.DB != 0 {
+			opts.DB = cfg.DB
+		}
+		if tlsConfig != nil {
+			opts.TLSConfig = tlsConfig
+		}
+		if opts.MasterName == "" {
+			return nil, errors.New("Redis sentinel mode requires a master name")
+		}
+		if cfg.ReadFromReplica {
+			// The failover cluster client sends writes to the master and
+			// spreads read-only commands across replicas.
+			opts.RouteRandomly = true
+			return redis.NewFailoverClusterClient(opts), nil
+		}
+		return redis.NewFailoverClient(opts), nil
+
+	case ModeCluster:
+		opts := &redis.ClusterOptions{}
+		if len(cfg.Addrs) > 0 {
+			opts.Addrs = cfg.Addrs
+		} else if opts, err = redis.ParseClusterURL(cfg.URL); err != nil {
+			return nil, fmt.Errorf("failed to parse Redis cluster URL: %w", err)
+		}
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("Redis cluster mode does not support selecting DB %d", cfg.DB)
+		}
+		setString(&opts.Username, cfg.Username)
+		setString(&opts.Password, cfg.Password)
+		if tlsConfig != nil {
+			opts.TLSConfig = tlsConfig
+		}
+		opts.ReadOnly = cfg.ReadFromReplica
+		return redis.NewClusterClient(opts), nil
+
+	default:
+		return nil, fmt.Errorf("unknown Redis mode %q", cfg.Mode)
+	}
+}
+
+func (cfg ClientConfig) tlsConfig() (*tls.Config, error) {
+	if !cfg.TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+	}
+
+	if cfg.TLSCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func pingClient(ctx context.Context, client redis.UniversalClient) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return shard.Ping(ctx).Err()
+		})
+	}
+	return client.Ping(ctx).Err()
+}
+
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
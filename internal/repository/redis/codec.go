@@ -0,0 +1,189 @@
+package redis
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/encryption"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+)
+
+// Every cached value is wrapped in a small envelope:
+//
+//	byte 0: envelopeMagic
+//	byte 1: schema version
+//	byte 2: codec
+//	byte 3: flags
+//	byte 4…: body
+//
+// Entries whose envelope is missing or carries another schema version are
+// treated as cache misses. Encrypted bodies are sealed after compression.
+const (
+	envelopeMagic      byte = 0xCA
+	envelopeHeaderSize      = 4
+
+	// SchemaVersion must be bumped whenever models.UserProfile changes, so
+	// that entries written by older builds are ignored instead of decoding
+	// with zero values.
+	SchemaVersion byte = 1
+)
+
+const (
+	flagCompressed byte = 1 << iota
+	flagNotFound
+	flagEncrypted
+)
+
+type Codec byte
+
+const (
+	CodecJSON Codec = iota + 1
+	CodecMsgpack
+)
+
+// errStaleEntry means the cached value was written with a different envelope
+// or schema version, or does not match the configured encryption.
+var errStaleEntry = errors.New("stale cache entry")
+
+func ParseCodec(name string) (Codec, error) {
+	switch name {
+	case "json":
+		return CodecJSON, nil
+	case "msgpack":
+		return CodecMsgpack, nil
+	default:
+		return 0, fmt.Errorf("unknown cache codec %q", name)
+	}
+}
+
+func (c Codec) String() string {
+	switch c {
+	case CodecJSON:
+		return "json"
+	case CodecMsgpack:
+		return "msgpack"
+	default:
+		return fmt.Sprintf("codec(%d)", byte(c))
+	}
+}
+
+// encodeProfile encrypts the body when cipher is set. aad binds the
+// ciphertext to the key it is stored under.
+func encodeProfile(profile *models.UserProfile, codec Codec, compress bool, cipher *encryption.FieldCipher, aad []byte) ([]byte, error) {
+	var body []byte
+	var err error
+
+	switch codec {
+	case CodecJSON:
+		body, err = json.Marshal(profile)
+	case CodecMsgpack:
+		// Structs are encoded as arrays, so field order is part of the schema.
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.UseArrayEncodedStructs(true)
+		enc.UseCompactInts(true)
+		err = enc.Encode(profile)
+		body = buf.Bytes()
+	default:
+		return nil, fmt.Errorf("unsupported cache codec %s", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	var flags byte
+	if compress {
+		if body, err = deflate(body); err != nil {
+			return nil, err
+		}
+		flags |= flagCompressed
+	}
+
+	if cipher != nil {
+		if body, err = cipher.Encrypt(body, aad); err != nil {
+			return nil, fmt.Errorf("failed to encrypt profile: %w", err)
+		}
+		flags |= flagEncrypted
+	}
+
+	return append([]byte{envelopeMagic, SchemaVersion, byte(codec), flags}, body...), nil
+}
+
+func encodeNotFound() []byte {
+	return []byte{envelopeMagic, SchemaVersion, 0, flagNotFound}
+}
+
+// decodeProfile returns ErrNegativeHit for negative entries and errStaleEntry
+// for values written with another envelope or schema version. When cipher is
+// set, plaintext entries are stale, as are encrypted ones when it is not.
+func decodeProfile(data []byte, cipher *encryption.FieldCipher, aad []byte) (*models.UserProfile, error) {
+	if len(data) < envelopeHeaderSize || data[0] != envelopeMagic || data[1] != SchemaVersion {
+		return nil, errStaleEntry
+	}
+
+	codec, flags, body := Codec(data[2]), data[3], data[envelopeHeaderSize:]
+	if flags&flagNotFound != 0 {
+		return nil, ErrNegativeHit
+	}
+
+	if (flags&flagEncrypted != 0) != (cipher != nil) {
+		return nil, errStaleEntry
+	}
+	if cipher != nil {
+		var err error
+		if body, err = cipher.Decrypt(body, aad); err != nil {
+			return nil, err
+		}
+	}
+
+	if flags&flagCompressed != 0 {
+		var err error
+		if body, err = inflate(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var profile models.UserProfile
+	var err error
+
+	switch codec {
+	case CodecJSON:
+		err = json.Unmarshal(body, &profile)
+	case CodecMsgpack:
+		err = msgpack.Unmarshal(body, &profile)
+	default:
+		return nil, fmt.Errorf("unsupported cache codec %s", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress profile: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress profile: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	out, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress profile: %w", err)
+	}
+	return out, nil
+}
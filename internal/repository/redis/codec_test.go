@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"testing"
+	"time"
+)
+
+func benchmarkProfile() *models.UserProfile {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	return &models.UserProfile{
+		ID:             "6f1c2a9e-8d4b-4c1e-9a7f-2b3c4d5e6f70",
+		UserID:         "b2a4c6d8-1e3f-4a5b-8c7d-9e0f1a2b3c4d",
+		FirstName:      "Anna",
+		LastName:       "Kowalska",
+		Phone:          "+48123456789",
+		DateOfBirth:    "1990-05-17",
+		AvatarURL:      "https://cdn.example.com/avatars/b2a4c6d8.png",
+		Address:        "ul. Marszalkowska 10/12",
+		City:           "Warsaw",
+		Country:        "PL",
+		PostalCode:     "00-590",
+		DrivingLicense: "PL-12345678",
+		CreatedAt:      created,
+		UpdatedAt:      created.Add(48 * time.Hour),
+	}
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	profile := benchmarkProfile()
+	for _, codec := range []Codec{CodecJSON, CodecMsgpack} {
+		for _, compress := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/compress=%t", codec, compress), func(t *testing.T) {
+				data, err := encodeProfile(profile, codec, compress, nil, nil)
+				if err != nil {
+					t.Fatalf("encodeProfile: %v", err)
+				}
+				got, err := decodeProfile(data, nil, nil)
+				if err != nil {
+					t.Fatalf("decodeProfile: %v", err)
+				}
+				// msgpack decodes times in the local zone
+				if !got.CreatedAt.Equal(profile.CreatedAt) || !got.UpdatedAt.Equal(profile.UpdatedAt) {
+					t.Errorf("decoded timestamps = %s, %s, want %s, %s", got.CreatedAt, got.UpdatedAt, profile.CreatedAt, profile.UpdatedAt)
+				}
+				got.CreatedAt, got.UpdatedAt = profile.CreatedAt, profile.UpdatedAt
+				if *got != *profile {
+					t.Errorf("decoded profile = %+v, want %+v", got, profile)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEncodeProfile(b *testing.B) {
+	profile := benchmarkProfile()
+	for _, codec := range []Codec{CodecJSON, CodecMsgpack} {
+		for _, compress := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/compress=%t", codec, compress), func(b *testing.B) {
+				var size int
+				b.ReportAllocs()
+				for b.Loop() {
+					data, err := encodeProfile(profile, codec, compress, nil, nil)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size = len(data)
+				}
+				b.ReportMetric(float64(size), "bytes/entry")
+			})
+		}
+	}
+}
+
+func BenchmarkDecodeProfile(b *testing.B) {
+	profile := benchmarkProfile()
+	for _, codec := range []Codec{CodecJSON, CodecMsgpack} {
+		for _, compress := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/compress=%t", codec, compress), func(b *testing.B) {
+				data, err := encodeProfile(profile, codec, compress, nil, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportAllocs()
+				for b.Loop() {
+					if _, err := decodeProfile(data, nil, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"log/slog"
+	"time"
+)
+
+const purgeBatchSize = 1000
+
+// AccessLogPurger deletes access records older than the retention period,
+// and the break-glass grants that expired before it.
+type AccessLogPurger struct {
+	repo      *postgres.ProfileRepository
+	retention time.Duration
+	logger    *slog.Logger
+}
+
+func NewAccessLogPurger(repo *postgres.ProfileRepository, retention time.Duration, logger *slog.Logger) *AccessLogPurger {
+	return &AccessLogPurger{repo: repo, retention: retention, logger: logger}
+}
+
+// Purge deletes expired records in batches and returns how many it deleted.
+func (p *AccessLogPurger) Purge(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-p.retention)
+
+	var purged int64
+	for {
+		n, err := p.repo.PurgeAccessLog(ctx, cutoff, purgeBatchSize)
+		purged += n
+		metrics.AuditRecordsPurged.Add(float64(n))
+		if err != nil {
+			return purged, err
+		}
+		if n < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeGrants deletes the break-glass grants that expired before the
+// retention period and returns how many it deleted. Records of reads under a
+// grant are made before it expires, so they are purged first.
+func (p *AccessLogPurger) PurgeGrants(ctx context.Context) (int64, error) {
+	return p.repo.PurgeBreakGlassGrants(ctx, time.Now().Add(-p.retention))
+}
+
+// Run purges expired records and grants every interval until ctx is cancelled.
+// Replicas may purge concurrently; each deletes whatever is still expired.
+func (p *AccessLogPurger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.logger.Error("Failed to purge access log", "error", err)
+		} else if purged > 0 {
+			p.logger.Info("Purged expired access records", "records", purged, "retention", p.retention)
+		}
+		grants, err := p.PurgeGrants(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.logger.Error("Failed to purge break-glass grants", "error", err)
+		} else if grants > 0 {
+			p.logger.Info("Purged expired break-glass grants", "grants", grants, "retention", p.retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
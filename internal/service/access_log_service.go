@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"github.com/google/uuid"
+	"log/slog"
+)
+
+const (
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+)
+
+// AccessLog is the access log API used by the gRPC and HTTP handlers.
+type AccessLog interface {
+	QueryAccessLog(ctx context.Context, filter models.AccessLogFilter) (*models.AccessLogPage, error)
+}
+
+// AccessLogService reads the records written by AuditedProfileService.
+type AccessLogService struct {
+	repo   *postgres.ProfileRepository
+	logger *slog.Logger
+}
+
+func NewAccessLogService(repo *postgres.ProfileRepository, logger *slog.Logger) *AccessLogService {
+	return &AccessLogService{repo: repo, logger: logger}
+}
+
+// QueryAccessLog returns a page of the access records matching filter,
+// newest first.
+func (s *AccessLogService) QueryAccessLog(ctx context.Context, filter models.AccessLogFilter) (_ *models.AccessLogPage, err error) {
+	ctx, span := tracer.Start(ctx, "AccessLogService.QueryAccessLog")
+	defer func() { endSpan(span, err) }()
+
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			return nil, fmt.Errorf("%w: user_id must be a UUID", ErrInvalidData)
+		}
+	}
+	if filter.Limit < 0 || filter.Limit > maxAccessLogLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidData, maxAccessLogLimit)
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAccessLogLimit
+	}
+
+	records, err := s.repo.QueryAccessLog(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to query access log", "error", err)
+		return nil, fmt.Errorf("failed to query access log: %w", err)
+	}
+
+	page := &models.AccessLogPage{Records: records}
+	if len(records) == filter.Limit {
+		page.NextBeforeID = records[len(records)-1].ID
+	}
+	return page, nil
+}
+
+// AuthorizedAccessLogService enforces the RBAC policy on access log queries.
+// Callers limited to their own profile must name it in the filter, so they
+// only see who accessed their own data.
+type AuthorizedAccessLogService struct {
+	authorizer
+	next AccessLog
+}
+
+func NewAuthorizedAccessLogService(next AccessLog, policy *authz.Policy, logger *slog.Logger) *AuthorizedAccessLogService {
+	return &AuthorizedAccessLogService{
+		authorizer: authorizer{policy: policy, logger: logger},
+		next:       next,
+	}
+}
+
+func (s *AuthorizedAccessLogService) QueryAccessLog(ctx context.Context, filter models.AccessLogFilter) (*models.AccessLogPage, error) {
+	if _, err := s.authorize(ctx, authz.MethodQueryAccessLog, filter.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.next.QueryAccessLog(ctx, filter)
+}
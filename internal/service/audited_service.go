@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/audit"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+// ErrAuditUnavailable means a read was refused because its access record
+// could not be recorded.
+var ErrAuditUnavailable = errors.New("access audit log unavailable")
+
+// AnonymousCaller is recorded as the caller when authentication is disabled.
+const AnonymousCaller = "anonymous"
+
+// AccessRecorder stores access records, e.g. audit.Writer.
+type AccessRecorder interface {
+	Record(ctx context.Context, rec models.AccessRecord) error
+}
+
+// AuditedProfileService records every read of personal data: who read which
+// fields of whose profile, when, and for the purpose stated in the request.
+// It wraps AuthorizedProfileService so that only the fields actually
+// returned are recorded.
+//
+// A read is served once its record has been accepted by the recorder, not
+// once it is stored. With audit.Writer, a read fails while the queue is full
+// or queued records cannot be written, but records queued when the process
+// crashes, or that cannot be written during shutdown, are lost.
+//
+// Reads under a break-glass grant are announced through breakGlass, which may
+// be nil, once their record has been accepted.
+type AuditedProfileService struct {
+	next       Profiles
+	recorder   AccessRecorder
+	breakGlass *BreakGlassService
+	logger     *slog.Logger
+}
+
+func NewAuditedProfileService(next Profiles, recorder AccessRecorder, breakGlass *BreakGlassService, logger *slog.Logger) *AuditedProfileService {
+	return &AuditedProfileService{
+		next:       next,
+		recorder:   recorder,
+		breakGlass: breakGlass,
+		logger:     logger,
+	}
+}
+
+func (s *AuditedProfileService) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	profile, err := s.next.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// With authentication, AuthorizedProfileService has already rejected
+	// reads under an invalid grant
+	var grantID string
+	if auth.PrincipalFromContext(ctx) != nil {
+		grantID = breakglass.GrantID(ctx)
+	}
+	if profile, err = s.record(ctx, authz.MethodGet, profile, grantID); err != nil {
+		return nil, err
+	}
+	if grantID != "" && s.breakGlass != nil {
+		s.breakGlass.Used(ctx, grantID)
+	}
+	return profile, nil
+}
+
+// CreateUserProfile and UpdateUserProfile return the stored profile, so they
+// are recorded like reads. When the record cannot be queued the change is
+// kept, but the profile is not returned.
+func (s *AuditedProfileService) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error) {
+	profile, err := s.next.CreateUserProfile(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.record(ctx, authz.MethodCreate, profile, "")
+}
+
+func (s *AuditedProfileService) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	profile, err := s.next.UpdateUserProfile(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.record(ctx, authz.MethodUpdate, profile, "")
+}
+
+func (s *AuditedProfileService) DeleteUserProfile(ctx context.Context, userID string) error {
+	return s.next.DeleteUserProfile(ctx, userID)
+}
+
+func (s *AuditedProfileService) FindProfileByPhone(ctx context.Context, phone, ownerID string) (*models.UserProfile, error) {
+	profile, err := s.next.FindProfileByPhone(ctx, phone, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.record(ctx, authz.MethodFindByPhone, profile, "")
+}
+
+func (s *AuditedProfileService) FindProfileByLicense(ctx context.Context, license, ownerID string) (*models.UserProfile, error) {
+	profile, err := s.next.FindProfileByLicense(ctx, license, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.record(ctx, authz.MethodFindByLicense, profile, "")
+}
+
+func (s *AuditedProfileService) record(ctx context.Context, method string, profile *models.UserProfile, breakGlassGrantID string) (*models.UserProfile, error) {
+	rec := models.AccessRecord{
+		AccessedAt:        time.Now().UTC(),
+		Caller:            AnonymousCaller,
+		Method:            method,
+		UserID:            profile.UserID,
+		Purpose:           audit.Purpose(ctx),
+		Fields:            returnedFields(profile),
+		RequestID:         logging.RequestID(ctx),
+		BreakGlassGrantID: breakGlassGrantID,
+	}
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		rec.Caller = callerName(principal)
+		rec.Roles = principal.Roles
+	}
+
+	if err := s.recorder.Record(ctx, rec); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to record profile access",
+			"method", method, "user_id", profile.UserID, "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrAuditUnavailable, err)
+	}
+	return profile, nil
+}
+
+// callerName identifies a caller by token subject, or by client certificate
+// identity for certificate-only callers.
+func callerName(p *auth.Principal) string {
+	if p.Subject == "" && p.CertIdentity != "" {
+		return "cert:" + p.CertIdentity
+	}
+	return p.Subject
+}
+
+// returnedFields lists the JSON names of the non-empty personal data fields
+// of profile.
+func returnedFields(profile *models.UserProfile) []string {
+	v := reflect.ValueOf(profile).Elem()
+	fields := make([]string, 0, v.NumField())
+	for i := range v.NumField() {
+		name := authz.JSONName(v.Type().Field(i))
+		if name == "" || authz.IsIdentityField(name) || v.Field(i).IsZero() {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
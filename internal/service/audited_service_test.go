@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"slices"
+	"testing"
+)
+
+type fakeRecorder struct {
+	records []models.AccessRecord
+	err     error
+}
+
+func (r *fakeRecorder) Record(ctx context.Context, rec models.AccessRecord) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.records = append(r.records, rec)
+	return nil
+}
+
+// fakeWriter stores profiles as requested.
+type fakeWriter struct {
+	Profiles
+}
+
+func (fakeWriter) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error) {
+	return &models.UserProfile{UserID: req.UserID, FirstName: req.FirstName, Phone: req.Phone}, nil
+}
+
+func (fakeWriter) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	return &models.UserProfile{UserID: userID, City: req.City}, nil
+}
+
+func TestAuditedWritesRecordReturnedFields(t *testing.T) {
+	recorder := &fakeRecorder{}
+	s := NewAuditedProfileService(fakeWriter{}, recorder, nil, testLogger)
+	ctx := context.Background()
+
+	if _, err := s.CreateUserProfile(ctx, &models.CreateProfileRequest{UserID: "user-1", FirstName: "Anna", Phone: "+48123456789"}); err != nil {
+		t.Fatalf("CreateUserProfile: %v", err)
+	}
+	if _, err := s.UpdateUserProfile(ctx, "user-1", &models.UpdateProfileRequest{City: "Krakow"}); err != nil {
+		t.Fatalf("UpdateUserProfile: %v", err)
+	}
+
+	if len(recorder.records) != 2 {
+		t.Fatalf("recorded %d accesses, want 2", len(recorder.records))
+	}
+	created, updated := recorder.records[0], recorder.records[1]
+	if created.Method != authz.MethodCreate || !slices.Equal(created.Fields, []string{"first_name", "phone"}) {
+		t.Errorf("create record = %s %v, want %s [first_name phone]", created.Method, created.Fields, authz.MethodCreate)
+	}
+	if updated.Method != authz.MethodUpdate || updated.UserID != "user-1" || !slices.Equal(updated.Fields, []string{"city"}) {
+		t.Errorf("update record = %s %s %v, want %s user-1 [city]", updated.Method, updated.UserID, updated.Fields, authz.MethodUpdate)
+	}
+}
+
+func TestAuditedWriteWithoutRecordFails(t *testing.T) {
+	s := NewAuditedProfileService(fakeWriter{}, &fakeRecorder{err: errors.New("queue full")}, nil, testLogger)
+
+	profile, err := s.UpdateUserProfile(context.Background(), "user-1", &models.UpdateProfileRequest{City: "Krakow"})
+	if !errors.Is(err, ErrAuditUnavailable) || profile != nil {
+		t.Errorf("UpdateUserProfile = %+v, %v, want ErrAuditUnavailable", profile, err)
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"log/slog"
+	"reflect"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Profiles is the profile API used by the gRPC and HTTP handlers. ProfileService
+// implements it directly and decorators such as AuthorizedProfileService wrap
+// it. The Find methods search every profile, or only the profile of ownerID
+// when it is set.
+type Profiles interface {
+	GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error)
+	CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error)
+	UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error)
+	DeleteUserProfile(ctx context.Context, userID string) error
+	FindProfileByPhone(ctx context.Context, phone, ownerID string) (*models.UserProfile, error)
+	FindProfileByLicense(ctx context.Context, license, ownerID string) (*models.UserProfile, error)
+}
+
+// AuthorizedProfileService enforces the RBAC policy for the caller stored in
+// the context by the auth layer. Fields the caller may not read are removed
+// from responses, and writes to fields it may not change are rejected.
+// Requests without a caller pass through unchanged, as when authentication
+// is disabled.
+//
+// A GetUserProfile request naming a valid break-glass grant for the caller
+// and profile bypasses the profile scope and field restrictions.
+type AuthorizedProfileService struct {
+	authorizer
+	next       Profiles
+	breakGlass *BreakGlassService
+}
+
+func NewAuthorizedProfileService(next Profiles, policy *authz.Policy, breakGlass *BreakGlassService, logger *slog.Logger) *AuthorizedProfileService {
+	return &AuthorizedProfileService{
+		authorizer: authorizer{policy: policy, logger: logger},
+		next:       next,
+		breakGlass: breakGlass,
+	}
+}
+
+func (s *AuthorizedProfileService) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	if grantID := breakglass.GrantID(ctx); grantID != "" && auth.PrincipalFromContext(ctx) != nil {
+		return s.getWithBreakGlass(ctx, grantID, userID)
+	}
+
+	grant, err := s.authorize(ctx, authz.MethodGet, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.next.GetUserProfile(ctx, userID)
+	if err != nil || grant == nil {
+		return profile, err
+	}
+	return stripProfile(profile, *grant), nil
+}
+
+func (s *AuthorizedProfileService) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error) {
+	grant, err := s.authorize(ctx, authz.MethodCreate, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if grant != nil {
+		if err := s.checkWrite(ctx, *grant, req); err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := s.next.CreateUserProfile(ctx, req)
+	if err != nil || grant == nil {
+		return profile, err
+	}
+	return stripProfile(profile, *grant), nil
+}
+
+func (s *AuthorizedProfileService) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	grant, err := s.authorize(ctx, authz.MethodUpdate, userID)
+	if err != nil {
+		return nil, err
+	}
+	if grant != nil {
+		if err := s.checkWrite(ctx, *grant, req); err != nil {
+			return nil, err
+		}
+	}
+
+	profile, err := s.next.UpdateUserProfile(ctx, userID, req)
+	if err != nil || grant == nil {
+		return profile, err
+	}
+	return stripProfile(profile, *grant), nil
+}
+
+func (s *AuthorizedProfileService) DeleteUserProfile(ctx context.Context, userID string) error {
+	if _, err := s.authorize(ctx, authz.MethodDelete, userID); err != nil {
+		return err
+	}
+
+	return s.next.DeleteUserProfile(ctx, userID)
+}
+
+func (s *AuthorizedProfileService) FindProfileByPhone(ctx context.Context, phone, ownerID string) (*models.UserProfile, error) {
+	return s.find(ctx, authz.MethodFindByPhone, phone, ownerID, s.next.FindProfileByPhone)
+}
+
+func (s *AuthorizedProfileService) FindProfileByLicense(ctx context.Context, license, ownerID string) (*models.UserProfile, error) {
+	return s.find(ctx, authz.MethodFindByLicense, license, ownerID, s.next.FindProfileByLicense)
+}
+
+// getWithBreakGlass reads a whole profile under a break-glass grant. The
+// caller must still be allowed to call GetUserProfile. The read is announced
+// by AuditedProfileService once its record has been accepted.
+func (s *AuthorizedProfileService) getWithBreakGlass(ctx context.Context, grantID, userID string) (*models.UserProfile, error) {
+	if _, err := s.authorizeMethod(ctx, authz.MethodGet); err != nil {
+		return nil, err
+	}
+	if s.breakGlass == nil {
+		return nil, ErrBreakGlassUnavailable
+	}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if _, err := s.breakGlass.Check(ctx, grantID, principal.Subject, userID); err != nil {
+		return nil, err
+	}
+
+	return s.next.GetUserProfile(ctx, userID)
+}
+
+// find runs a lookup whose target is only known from its result. Callers
+// limited to their own profile only search their own profile, so lookups
+// cannot reveal whether someone else's phone or license is on file.
+func (s *AuthorizedProfileService) find(
+	ctx context.Context,
+	method, value, ownerID string,
+	find func(context.Context, string, string) (*models.UserProfile, error),
+) (*models.UserProfile, error) {
+	grant, err := s.authorizeMethod(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return find(ctx, value, ownerID)
+	}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if !grant.CanAccessAnyUser() {
+		if principal.Subject == "" || (ownerID != "" && ownerID != principal.Subject) {
+			return nil, ErrProfileNotFound
+		}
+		ownerID = principal.Subject
+	}
+
+	profile, err := find(ctx, value, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !grant.CanAccessUser(principal.Subject, profile.UserID) {
+		return nil, ErrProfileNotFound
+	}
+	return stripProfile(profile, *grant), nil
+}
+
+// authorizer checks callers against the RBAC policy.
+type authorizer struct {
+	policy *authz.Policy
+	logger *slog.Logger
+}
+
+// authorizeMethod checks that the caller may call method and returns its
+// grant, or nil when there is no authenticated caller.
+func (s *authorizer) authorizeMethod(ctx context.Context, method string) (*authz.Grant, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, nil
+	}
+
+	grant := s.policy.Grant(principal.Roles, principal.CertIdentity)
+
+	if !grant.CanCall(method) {
+		logging.FromContext(ctx, s.logger).Warn("RBAC denied method",
+			"method", method, "subject", principal.Subject, "roles", principal.Roles)
+		return nil, fmt.Errorf("%w: %s is not allowed", ErrPermissionDenied, method)
+	}
+
+	return &grant, nil
+}
+
+// authorize checks that the caller may call method on the profile of userID
+// and returns its grant, or nil when there is no authenticated caller.
+func (s *authorizer) authorize(ctx context.Context, method, userID string) (*authz.Grant, error) {
+	grant, err := s.authorizeMethod(ctx, method)
+	if err != nil || grant == nil {
+		return grant, err
+	}
+
+	principal := auth.PrincipalFromContext(ctx)
+	if !grant.CanAccessUser(principal.Subject, userID) {
+		logging.FromContext(ctx, s.logger).Warn("RBAC denied access to another user's profile",
+			"method", method, "subject", principal.Subject, "roles", principal.Roles, "user_id", userID)
+		return nil, fmt.Errorf("%w: caller may only access its own profile", ErrPermissionDenied)
+	}
+
+	return grant, nil
+}
+
+// checkWrite rejects requests that set a field the grant may not write. The
+// user ID only selects the profile and is not checked. Updates set the fields
+// in their field mask, including empty ones that clear a stored value.
+func (s *AuthorizedProfileService) checkWrite(ctx context.Context, grant authz.Grant, req any) error {
+	update, _ := req.(*models.UpdateProfileRequest)
+	v := reflect.ValueOf(req).Elem()
+	for i := range v.NumField() {
+		name := authz.JSONName(v.Type().Field(i))
+		set := !v.Field(i).IsZero()
+		if update != nil {
+			set = update.Updates(name)
+		}
+		if name == "" || name == "user_id" || !set {
+			continue
+		}
+		if !grant.CanWrite(name) {
+			logging.FromContext(ctx, s.logger).Warn("RBAC denied field write", "field", name)
+			return fmt.Errorf("%w: not allowed to change %s", ErrPermissionDenied, name)
+		}
+	}
+	return nil
+}
+
+// stripProfile returns a copy of profile without the fields the grant may
+// not read.
+func stripProfile(profile *models.UserProfile, grant authz.Grant) *models.UserProfile {
+	stripped := *profile
+
+	v := reflect.ValueOf(&stripped).Elem()
+	for i := range v.NumField() {
+		if name := authz.JSONName(v.Type().Field(i)); name != "" && !grant.CanRead(name) {
+			v.Field(i).SetZero()
+		}
+	}
+
+	return &stripped
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fakeFinder returns the profiles matching a phone number, most recently
+// updated first, and records the owner each search was limited to.
+type fakeFinder struct {
+	Profiles
+	matches []*models.UserProfile
+	ownerID string
+}
+
+func (f *fakeFinder) FindProfileByPhone(ctx context.Context, phone, ownerID string) (*models.UserProfile, error) {
+	f.ownerID = ownerID
+	for _, p := range f.matches {
+		if ownerID == "" || p.UserID == ownerID {
+			return p, nil
+		}
+	}
+	return nil, ErrProfileNotFound
+}
+
+func withRoles(subject string, roles ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, Roles: roles})
+}
+
+func TestFindLimitsOwnScopeToCallersProfile(t *testing.T) {
+	// Two profiles share the phone number, and someone else's was updated last
+	finder := &fakeFinder{matches: []*models.UserProfile{
+		{UserID: "user-2", Phone: "+48123456789"},
+		{UserID: "user-1", Phone: "+48123456789"},
+	}}
+	s := NewAuthorizedProfileService(finder, authz.DefaultPolicy(), nil, testLogger)
+
+	profile, err := s.FindProfileByPhone(withRoles("user-1", auth.RoleUser), "+48123456789", "")
+	if err != nil {
+		t.Fatalf("FindProfileByPhone: %v", err)
+	}
+	if profile.UserID != "user-1" || finder.ownerID != "user-1" {
+		t.Errorf("found %s searching owner %q, want user-1 for both", profile.UserID, finder.ownerID)
+	}
+
+	if _, err := s.FindProfileByPhone(withRoles("user-1", auth.RoleUser), "+48123456789", "user-2"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("search limited to another user = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestFindOwnScopeHidesOtherProfiles(t *testing.T) {
+	finder := &fakeFinder{matches: []*models.UserProfile{{UserID: "user-2", Phone: "+48123456789"}}}
+	s := NewAuthorizedProfileService(finder, authz.DefaultPolicy(), nil, testLogger)
+
+	if _, err := s.FindProfileByPhone(withRoles("user-1", auth.RoleUser), "+48123456789", ""); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("FindProfileByPhone = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestFindAnyScopeSearchesEveryProfile(t *testing.T) {
+	finder := &fakeFinder{matches: []*models.UserProfile{{UserID: "user-2", Phone: "+48123456789"}}}
+	s := NewAuthorizedProfileService(finder, authz.DefaultPolicy(), nil, testLogger)
+
+	profile, err := s.FindProfileByPhone(withRoles("billing", auth.RoleService), "+48123456789", "")
+	if err != nil {
+		t.Fatalf("FindProfileByPhone: %v", err)
+	}
+	if profile.UserID != "user-2" || finder.ownerID != "" {
+		t.Errorf("found %s searching owner %q, want user-2 without an owner", profile.UserID, finder.ownerID)
+	}
+}
+
+// fakeUpdater records the update it receives.
+type fakeUpdater struct {
+	Profiles
+	updated *models.UpdateProfileRequest
+}
+
+func (f *fakeUpdater) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
+	f.updated = req
+	return &models.UserProfile{UserID: userID}, nil
+}
+
+func TestUpdateChecksFieldMask(t *testing.T) {
+	policy := &authz.Policy{Roles: map[string]authz.RolePolicy{
+		"editor": {Methods: []string{authz.MethodUpdate}, Scope: authz.ScopeAny, ReadFields: []string{"*"}, WriteFields: []string{"first_name"}},
+	}}
+	tests := []struct {
+		name    string
+		req     models.UpdateProfileRequest
+		allowed bool
+	}{
+		{name: "writable field", req: models.UpdateProfileRequest{FirstName: "Anna", Fields: []string{"first_name"}}, allowed: true},
+		{name: "clear writable field", req: models.UpdateProfileRequest{Fields: []string{"first_name"}}, allowed: true},
+		{name: "clear forbidden field", req: models.UpdateProfileRequest{Fields: []string{"first_name", "address"}}},
+		{name: "set forbidden field", req: models.UpdateProfileRequest{City: "Krakow", Fields: []string{"city"}}},
+		{name: "every field", req: models.UpdateProfileRequest{FirstName: "Anna"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := &fakeUpdater{}
+			s := NewAuthorizedProfileService(updater, policy, nil, testLogger)
+
+			_, err := s.UpdateUserProfile(withRoles("editor", "editor"), "user-1", &tt.req)
+			if tt.allowed && err != nil {
+				t.Errorf("UpdateUserProfile = %v, want allowed", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("UpdateUserProfile = %v, want ErrPermissionDenied", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/auth"
+	"github.com/Brrocat/user-profile-service/internal/authz"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/google/uuid"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrBreakGlassUnavailable means break-glass grants cannot be used, because
+// authentication or the access audit log is disabled.
+var ErrBreakGlassUnavailable = errors.New("break-glass access requires authentication and the access audit log")
+
+// ErrGrantNotFound means there is no break-glass grant with the requested ID
+// that the caller may revoke.
+var ErrGrantNotFound = errors.New("break-glass grant not found")
+
+const (
+	maxTicketLength = 100
+	maxReasonLength = 1000
+)
+
+// BreakGlassStore stores grants, e.g. postgres.ProfileRepository.
+type BreakGlassStore interface {
+	CreateBreakGlassGrant(ctx context.Context, subject string, req *models.BreakGlassRequest, expiresAt time.Time) (*models.BreakGlassGrant, error)
+	GetBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error)
+	RevokeBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error)
+}
+
+// BreakGlass is the break-glass API used by the HTTP handler.
+type BreakGlass interface {
+	RequestBreakGlass(ctx context.Context, req *models.BreakGlassRequest) (*models.BreakGlassGrant, error)
+	RevokeBreakGlass(ctx context.Context, id string) (*models.BreakGlassGrant, error)
+}
+
+type BreakGlassOptions struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// BreakGlassService issues, checks and revokes break-glass grants, which let
+// a caller read every field of one profile for a short time. Issuing, using
+// and revoking a grant each send a notification.
+type BreakGlassService struct {
+	repo     BreakGlassStore
+	notifier *breakglass.Notifier
+	opts     BreakGlassOptions
+	logger   *slog.Logger
+}
+
+func NewBreakGlassService(repo BreakGlassStore, notifier *breakglass.Notifier, opts BreakGlassOptions, logger *slog.Logger) (*BreakGlassService, error) {
+	if opts.DefaultTTL <= 0 || opts.MaxTTL < opts.DefaultTTL {
+		return nil, fmt.Errorf("break-glass TTLs must satisfy 0 < default <= max, got %s and %s", opts.DefaultTTL, opts.MaxTTL)
+	}
+
+	return &BreakGlassService{
+		repo:     repo,
+		notifier: notifier,
+		opts:     opts,
+		logger:   logger,
+	}, nil
+}
+
+// Grant issues a grant for subject to read the profile in req.
+func (s *BreakGlassService) Grant(ctx context.Context, subject string, req *models.BreakGlassRequest) (*models.BreakGlassGrant, error) {
+	ttl, err := s.validate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := s.repo.CreateBreakGlassGrant(ctx, subject, req, time.Now().Add(ttl))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create break-glass grant", "error", err)
+		return nil, fmt.Errorf("failed to create break-glass grant: %w", err)
+	}
+
+	s.notify(ctx, breakglass.EventGranted, grant)
+	return grant, nil
+}
+
+func (s *BreakGlassService) validate(req *models.BreakGlassRequest) (time.Duration, error) {
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		return 0, fmt.Errorf("%w: user_id must be a UUID", ErrInvalidData)
+	}
+	req.Ticket = strings.TrimSpace(req.Ticket)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Ticket == "" || len(req.Ticket) > maxTicketLength {
+		return 0, fmt.Errorf("%w: ticket is required and must be at most %d bytes", ErrInvalidData, maxTicketLength)
+	}
+	if req.Reason == "" || len(req.Reason) > maxReasonLength {
+		return 0, fmt.Errorf("%w: reason is required and must be at most %d bytes", ErrInvalidData, maxReasonLength)
+	}
+
+	if req.TTL == "" {
+		return s.opts.DefaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil || ttl <= 0 || ttl > s.opts.MaxTTL {
+		return 0, fmt.Errorf("%w: ttl must be a duration of at most %s", ErrInvalidData, s.opts.MaxTTL)
+	}
+	return ttl, nil
+}
+
+// Check returns the grant with id if it lets subject read the profile of
+// userID now: it names both, has not expired and has not been revoked.
+func (s *BreakGlassService) Check(ctx context.Context, id, subject, userID string) (*models.BreakGlassGrant, error) {
+	denied := fmt.Errorf("%w: break-glass grant is invalid or expired", ErrPermissionDenied)
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, denied
+	}
+	grant, err := s.repo.GetBreakGlassGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if grant == nil || grant.Subject != subject || !sameUUID(grant.UserID, userID) ||
+		time.Now().After(grant.ExpiresAt) || grant.RevokedAt != nil {
+		logging.FromContext(ctx, s.logger).Warn("Rejected break-glass grant",
+			"grant_id", id, "subject", subject, "user_id", userID)
+		return nil, denied
+	}
+	return grant, nil
+}
+
+// Used announces a read under the grant with id. It is called once the read
+// has been recorded in the access log.
+func (s *BreakGlassService) Used(ctx context.Context, id string) {
+	grant, err := s.repo.GetBreakGlassGrant(ctx, id)
+	if err != nil || grant == nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to announce break-glass read",
+			"grant_id", id, "error", err)
+		return
+	}
+	s.notify(ctx, breakglass.EventUsed, grant)
+}
+
+// Revoke ends the grant with id before it expires. With a subject, only that
+// subject's grants can be revoked. Revoking a revoked grant returns it
+// unchanged.
+func (s *BreakGlassService) Revoke(ctx context.Context, id, subject string) (*models.BreakGlassGrant, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrGrantNotFound
+	}
+	grant, err := s.repo.GetBreakGlassGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || (subject != "" && grant.Subject != subject) {
+		return nil, ErrGrantNotFound
+	}
+	if grant.RevokedAt != nil {
+		return grant, nil
+	}
+
+	if grant, err = s.repo.RevokeBreakGlassGrant(ctx, id); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to revoke break-glass grant", "grant_id", id, "error", err)
+		return nil, fmt.Errorf("failed to revoke break-glass grant: %w", err)
+	}
+	if grant == nil {
+		return nil, ErrGrantNotFound
+	}
+
+	s.notify(ctx, breakglass.EventRevoked, grant)
+	return grant, nil
+}
+
+func (s *BreakGlassService) notify(ctx context.Context, eventType string, grant *models.BreakGlassGrant) {
+	s.notifier.Notify(breakglass.Event{
+		Type:      eventType,
+		GrantID:   grant.ID,
+		Subject:   grant.Subject,
+		UserID:    grant.UserID,
+		Ticket:    grant.Ticket,
+		Reason:    grant.Reason,
+		ExpiresAt: grant.ExpiresAt,
+		RequestID: logging.RequestID(ctx),
+		Time:      time.Now().UTC(),
+	})
+}
+
+// AuthorizedBreakGlassService enforces the RBAC policy on break-glass
+// requests. Grants are issued to the caller's token subject. Callers that may
+// request grants can revoke their own, and callers allowed to call
+// RevokeBreakGlass can revoke any.
+type AuthorizedBreakGlassService struct {
+	authorizer
+	grants *BreakGlassService
+}
+
+func NewAuthorizedBreakGlassService(grants *BreakGlassService, policy *authz.Policy, logger *slog.Logger) *AuthorizedBreakGlassService {
+	return &AuthorizedBreakGlassService{
+		authorizer: authorizer{policy: policy, logger: logger},
+		grants:     grants,
+	}
+}
+
+func (s *AuthorizedBreakGlassService) RequestBreakGlass(ctx context.Context, req *models.BreakGlassRequest) (*models.BreakGlassGrant, error) {
+	grant, err := s.authorize(ctx, authz.MethodRequestBreakGlass, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, ErrBreakGlassUnavailable
+	}
+
+	subject, err := grantSubject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.grants.Grant(ctx, subject, req)
+}
+
+func (s *AuthorizedBreakGlassService) RevokeBreakGlass(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	grant, err := s.authorizeMethod(ctx, authz.MethodRevokeBreakGlass)
+	if err == nil {
+		if grant == nil {
+			return nil, ErrBreakGlassUnavailable
+		}
+		return s.grants.Revoke(ctx, id, "")
+	}
+
+	// Callers that can request grants may revoke their own
+	if _, ownErr := s.authorizeMethod(ctx, authz.MethodRequestBreakGlass); ownErr != nil {
+		return nil, err
+	}
+	subject, err := grantSubject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.grants.Revoke(ctx, id, subject)
+}
+
+// grantSubject returns the token subject of the caller, whom grants are
+// issued to.
+func grantSubject(ctx context.Context) (string, error) {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal.Subject == "" {
+		return "", fmt.Errorf("%w: break-glass grants require a token subject", ErrPermissionDenied)
+	}
+	return principal.Subject, nil
+}
+
+func sameUUID(a, b string) bool {
+	ua, errA := uuid.Parse(a)
+	ub, errB := uuid.Parse(b)
+	return errA == nil && errB == nil && ua == ub
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/breakglass"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"testing"
+	"time"
+)
+
+const (
+	testGrantID = "0b7e2f4c-5a6d-4e8f-9a1b-2c3d4e5f6a7b"
+	testUserID  = "5f0c6a1e-3b2d-4c8e-9f7a-1d2e3f4a5b6c"
+)
+
+// fakeGrants stores grants by ID.
+type fakeGrants struct {
+	grants map[string]*models.BreakGlassGrant
+}
+
+func (f *fakeGrants) CreateBreakGlassGrant(ctx context.Context, subject string, req *models.BreakGlassRequest, expiresAt time.Time) (*models.BreakGlassGrant, error) {
+	grant := &models.BreakGlassGrant{ID: testGrantID, Subject: subject, UserID: req.UserID, ExpiresAt: expiresAt}
+	f.grants[grant.ID] = grant
+	return grant, nil
+}
+
+func (f *fakeGrants) GetBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	return f.grants[id], nil
+}
+
+func (f *fakeGrants) RevokeBreakGlassGrant(ctx context.Context, id string) (*models.BreakGlassGrant, error) {
+	grant := f.grants[id]
+	if grant != nil && grant.RevokedAt == nil {
+		now := time.Now()
+		grant.RevokedAt = &now
+	}
+	return grant, nil
+}
+
+func newTestBreakGlass(t *testing.T, grant *models.BreakGlassGrant) *BreakGlassService {
+	t.Helper()
+	store := &fakeGrants{grants: map[string]*models.BreakGlassGrant{grant.ID: grant}}
+	s, err := NewBreakGlassService(store, breakglass.NewNotifier(testLogger, "", testLogger), BreakGlassOptions{
+		DefaultTTL: 15 * time.Minute,
+		MaxTTL:     time.Hour,
+	}, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBreakGlassCheck(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name    string
+		grant   models.BreakGlassGrant
+		id      string
+		subject string
+		userID  string
+		valid   bool
+	}{
+		{name: "valid", valid: true},
+		{name: "user ID in another case", userID: "5F0C6A1E-3B2D-4C8E-9F7A-1D2E3F4A5B6C", valid: true},
+		{name: "expired", grant: models.BreakGlassGrant{ExpiresAt: time.Now().Add(-time.Second)}},
+		{name: "revoked", grant: models.BreakGlassGrant{RevokedAt: &revokedAt}},
+		{name: "other subject", subject: "intruder"},
+		{name: "other user", userID: "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"},
+		{name: "unknown grant", id: "1c2d3e4f-5a6b-4c7d-8e9f-0a1b2c3d4e5f"},
+		{name: "malformed grant ID", id: "grant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant := tt.grant
+			grant.ID = testGrantID
+			grant.Subject = "agent"
+			grant.UserID = testUserID
+			if grant.ExpiresAt.IsZero() {
+				grant.ExpiresAt = time.Now().Add(time.Minute)
+			}
+			s := newTestBreakGlass(t, &grant)
+
+			id, subject, userID := testGrantID, "agent", testUserID
+			if tt.id != "" {
+				id = tt.id
+			}
+			if tt.subject != "" {
+				subject = tt.subject
+			}
+			if tt.userID != "" {
+				userID = tt.userID
+			}
+
+			_, err := s.Check(context.Background(), id, subject, userID)
+			if tt.valid && err != nil {
+				t.Errorf("Check = %v, want the grant", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("Check = %v, want ErrPermissionDenied", err)
+			}
+		})
+	}
+}
+
+func TestBreakGlassRevokeLimitsSubject(t *testing.T) {
+	s := newTestBreakGlass(t, &models.BreakGlassGrant{
+		ID: testGrantID, Subject: "agent", UserID: testUserID, ExpiresAt: time.Now().Add(time.Minute),
+	})
+	ctx := context.Background()
+
+	if _, err := s.Revoke(ctx, testGrantID, "intruder"); !errors.Is(err, ErrGrantNotFound) {
+		t.Fatalf("Revoke by another subject = %v, want ErrGrantNotFound", err)
+	}
+	grant, err := s.Revoke(ctx, testGrantID, "agent")
+	if err != nil || grant.RevokedAt == nil {
+		t.Fatalf("Revoke by its subject = %+v, %v, want a revoked grant", grant, err)
+	}
+	if _, err := s.Check(ctx, testGrantID, "agent", testUserID); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Check after Revoke = %v, want ErrPermissionDenied", err)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"github.com/Brrocat/user-profile-service/internal/repository/redis"
+	"log/slog"
+	"time"
+)
+
+type RepairMode string
+
+const (
+	RepairNone    RepairMode = "none"
+	RepairRewrite RepairMode = "rewrite"
+	RepairEvict   RepairMode = "evict"
+)
+
+func ParseRepairMode(mode string) (RepairMode, error) {
+	switch RepairMode(mode) {
+	case RepairNone, RepairRewrite, RepairEvict:
+		return RepairMode(mode), nil
+	default:
+		return "", fmt.Errorf("unknown repair mode %q", mode)
+	}
+}
+
+const (
+	// MismatchFields means both sides have the profile but some fields differ.
+	MismatchFields = "fields"
+	// MismatchOrphan means the cache holds a profile that is gone from the database.
+	MismatchOrphan = "orphan"
+	// MismatchNegative means the cache claims a profile is missing while the database has it.
+	MismatchNegative = "negative"
+	// MismatchCorrupt means the cached value cannot be decoded.
+	MismatchCorrupt = "corrupt"
+)
+
+type CacheMismatch struct {
+	UserID string
+	Kind   string
+	Fields []string
+}
+
+type VerifyReport struct {
+	Checked    int
+	Mismatches []CacheMismatch
+	Repaired   int
+}
+
+func (r *VerifyReport) DriftRatio() float64 {
+	if r.Checked == 0 {
+		return 0
+	}
+	return float64(len(r.Mismatches)) / float64(r.Checked)
+}
+
+// CacheVerifier compares cached profiles with the database and optionally
+// repairs the entries that drifted.
+type CacheVerifier struct {
+	profileRepo *postgres.ProfileRepository
+	cacheRepo   *redis.CacheRepository
+	repair      RepairMode
+	logger      *slog.Logger
+}
+
+func NewCacheVerifier(
+	profileRepo *postgres.ProfileRepository,
+	cacheRepo *redis.CacheRepository,
+	repair RepairMode,
+	logger *slog.Logger,
+) *CacheVerifier {
+	return &CacheVerifier{
+		profileRepo: profileRepo,
+		cacheRepo:   cacheRepo,
+		repair:      repair,
+		logger:      logger,
+	}
+}
+
+// VerifyAll checks every profile currently in the cache.
+func (v *CacheVerifier) VerifyAll(ctx context.Context, batchSize int) (*VerifyReport, error) {
+	report := &VerifyReport{}
+
+	err := v.cacheRepo.ScanUserIDs(ctx, batchSize, func(userIDs []string) error {
+		return v.verify(ctx, userIDs, report)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	v.record(report)
+	return report, nil
+}
+
+// VerifyUsers checks the given profiles. Users absent from the cache are
+// skipped.
+func (v *CacheVerifier) VerifyUsers(ctx context.Context, userIDs []string) (*VerifyReport, error) {
+	report := &VerifyReport{}
+	if err := v.verify(ctx, userIDs, report); err != nil {
+		return report, err
+	}
+
+	v.record(report)
+	return report, nil
+}
+
+// RunSampler verifies sampleSize random profiles every interval until ctx is done.
+func (v *CacheVerifier) RunSampler(ctx context.Context, interval time.Duration, sampleSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		userIDs, err := v.profileRepo.SampleUserIDs(ctx, sampleSize)
+		if err != nil {
+			v.logger.Warn("Failed to sample profiles for cache verification", "error", err)
+			continue
+		}
+
+		report, err := v.VerifyUsers(ctx, userIDs)
+		if err != nil {
+			v.logger.Warn("Cache verification failed", "error", err)
+			continue
+		}
+
+		v.logger.Debug("Cache sample verified",
+			"checked", report.Checked,
+			"mismatches", len(report.Mismatches),
+			"repaired", report.Repaired,
+		)
+	}
+}
+
+func (v *CacheVerifier) verify(ctx context.Context, userIDs []string, report *VerifyReport) error {
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		cached, err := v.cacheRepo.GetCachedProfile(ctx, userID)
+		negative := errors.Is(err, redis.ErrNegativeHit)
+		corrupt := errors.Is(err, redis.ErrCorruptEntry)
+		if err != nil && !negative && !corrupt {
+			return fmt.Errorf("failed to read cached profile %s: %w", userID, err)
+		}
+
+		if cached == nil && !negative && !corrupt {
+			continue
+		}
+		report.Checked++
+
+		var stored *models.UserProfile
+		if !corrupt {
+			if stored, err = v.profileRepo.GetProfileByUserID(ctx, userID); err != nil {
+				return fmt.Errorf("failed to read profile %s: %w", userID, err)
+			}
+		}
+
+		var mismatch *CacheMismatch
+		switch {
+		case corrupt:
+			// Nothing to compare, so the entry is evicted in both repair modes
+			mismatch = &CacheMismatch{UserID: userID, Kind: MismatchCorrupt}
+		case negative && stored != nil:
+			mismatch = &CacheMismatch{UserID: userID, Kind: MismatchNegative}
+		case cached != nil && stored == nil:
+			mismatch = &CacheMismatch{UserID: userID, Kind: MismatchOrphan}
+		case cached != nil:
+			if fields := diffProfiles(cached, stored); len(fields) > 0 {
+				mismatch = &CacheMismatch{UserID: userID, Kind: MismatchFields, Fields: fields}
+			}
+		}
+		if mismatch == nil {
+			continue
+		}
+
+		report.Mismatches = append(report.Mismatches, *mismatch)
+		metrics.CacheVerifyMismatches.WithLabelValues(mismatch.Kind).Inc()
+		v.logger.Warn("Cached profile differs from database",
+			"user_id", userID,
+			"kind", mismatch.Kind,
+			"fields", mismatch.Fields,
+		)
+
+		repaired, err := v.repairEntry(ctx, userID, stored)
+		if err != nil {
+			v.logger.Warn("Failed to repair cached profile", "user_id", userID, "error", err)
+			continue
+		}
+		if repaired {
+			report.Repaired++
+		}
+	}
+
+	return nil
+}
+
+func (v *CacheVerifier) repairEntry(ctx context.Context, userID string, stored *models.UserProfile) (bool, error) {
+	switch {
+	case v.repair == RepairNone:
+		return false, nil
+	case v.repair == RepairRewrite && stored != nil:
+		if err := v.cacheRepo.CacheProfile(ctx, stored); err != nil {
+			return false, err
+		}
+		metrics.CacheVerifyRepairs.WithLabelValues(string(RepairRewrite)).Inc()
+	default:
+		// Orphans cannot be rewritten, they are evicted in both modes
+		if err := v.cacheRepo.DeleteCachedProfile(ctx, userID); err != nil {
+			return false, err
+		}
+		metrics.CacheVerifyRepairs.WithLabelValues(string(RepairEvict)).Inc()
+	}
+	return true, nil
+}
+
+func (v *CacheVerifier) record(report *VerifyReport) {
+	metrics.CacheVerifyChecked.Add(float64(report.Checked))
+	metrics.CacheDriftRatio.Set(report.DriftRatio())
+}
+
+// diffProfiles returns the JSON names of fields that differ.
+func diffProfiles(cached, stored *models.UserProfile) []string {
+	var fields []string
+
+	check := func(name string, equal bool) {
+		if !equal {
+			fields = append(fields, name)
+		}
+	}
+
+	check("id", cached.ID == stored.ID)
+	check("user_id", cached.UserID == stored.UserID)
+	check("first_name", cached.FirstName == stored.FirstName)
+	check("last_name", cached.LastName == stored.LastName)
+	check("phone", cached.Phone == stored.Phone)
+	check("date_of_birth", cached.DateOfBirth == stored.DateOfBirth)
+	check("avatar_url", cached.AvatarURL == stored.AvatarURL)
+	check("address", cached.Address == stored.Address)
+	check("city", cached.City == stored.City)
+	check("country", cached.Country == stored.Country)
+	check("postal_code", cached.PostalCode == stored.PostalCode)
+	check("driving_license", cached.DrivingLicense == stored.DrivingLicense)
+	check("created_at", cached.CreatedAt.Equal(stored.CreatedAt))
+	check("updated_at", cached.UpdatedAt.Equal(stored.UpdatedAt))
+
+	return fields
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"github.com/Brrocat/user-profile-service/internal/repository/redis"
+	"golang.org/x/time/rate"
+	"log/slog"
+	"time"
+)
+
+type WarmupOptions struct {
+	// Limit is the number of most recently updated profiles to preload.
+	Limit int
+	// BatchSize is the number of profiles read and cached per round trip.
+	BatchSize int
+	// RowsPerSecond caps the database read rate. Zero means unlimited.
+	RowsPerSecond int
+}
+
+// CacheWarmer preloads recently updated profiles into the cache, e.g. after
+// a deploy or a Redis flush.
+type CacheWarmer struct {
+	profileRepo *postgres.ProfileRepository
+	cacheRepo   *redis.CacheRepository
+	opts        WarmupOptions
+	logger      *slog.Logger
+}
+
+func NewCacheWarmer(
+	profileRepo *postgres.ProfileRepository,
+	cacheRepo *redis.CacheRepository,
+	opts WarmupOptions,
+	logger *slog.Logger,
+) (*CacheWarmer, error) {
+	if opts.Limit <= 0 || opts.BatchSize <= 0 {
+		return nil, errors.New("warm-up limit and batch size must be positive")
+	}
+	if opts.RowsPerSecond < 0 {
+		return nil, errors.New("warm-up rate must not be negative")
+	}
+
+	return &CacheWarmer{
+		profileRepo: profileRepo,
+		cacheRepo:   cacheRepo,
+		opts:        opts,
+		logger:      logger,
+	}, nil
+}
+
+// Run preloads profiles until the limit is reached, the table is exhausted
+// or ctx is cancelled. It returns the number of profiles cached.
+func (w *CacheWarmer) Run(ctx context.Context) (int, error) {
+	limiter := rate.NewLimiter(rate.Inf, w.opts.BatchSize)
+	if w.opts.RowsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(w.opts.RowsPerSecond), max(w.opts.BatchSize, w.opts.RowsPerSecond))
+	}
+
+	start := time.Now()
+	w.logger.Info("Cache warm-up started", "limit", w.opts.Limit, "batch_size", w.opts.BatchSize, "rows_per_second", w.opts.RowsPerSecond)
+	metrics.CacheWarmupProgress.Set(0)
+
+	var cursor *postgres.ProfileCursor
+	loaded := 0
+
+	for loaded < w.opts.Limit {
+		batchSize := min(w.opts.BatchSize, w.opts.Limit-loaded)
+		if err := limiter.WaitN(ctx, batchSize); err != nil {
+			return loaded, fmt.Errorf("cache warm-up interrupted: %w", err)
+		}
+
+		profiles, err := w.profileRepo.ListRecentlyUpdated(ctx, cursor, batchSize)
+		if err != nil {
+			return loaded, err
+		}
+		if len(profiles) == 0 {
+			break
+		}
+
+		userIDs := make([]string, len(profiles))
+		for i, profile := range profiles {
+			userIDs[i] = profile.UserID
+		}
+
+		if err := w.cacheRepo.CacheProfileList(ctx, userIDs, profiles); err != nil {
+			return loaded, err
+		}
+
+		last := profiles[len(profiles)-1]
+		cursor = &postgres.ProfileCursor{UpdatedAt: last.UpdatedAt, UserID: last.UserID}
+		loaded += len(profiles)
+
+		metrics.CacheWarmupProfiles.Add(float64(len(profiles)))
+		metrics.CacheWarmupProgress.Set(float64(loaded) / float64(w.opts.Limit))
+		w.logger.Info("Cache warm-up progress", "loaded", loaded, "limit", w.opts.Limit)
+
+		if len(profiles) < batchSize {
+			break
+		}
+	}
+
+	elapsed := time.Since(start)
+	metrics.CacheWarmupProgress.Set(1)
+	metrics.CacheWarmupDuration.Set(elapsed.Seconds())
+	w.logger.Info("Cache warm-up finished", "loaded", loaded, "duration", elapsed)
+
+	return loaded, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"golang.org/x/time/rate"
+	"log/slog"
+	"time"
+)
+
+type KeyRotationOptions struct {
+	// BatchSize is the number of profiles rewrapped per transaction.
+	BatchSize int
+	// RowsPerSecond caps the database write rate. Zero means unlimited.
+	RowsPerSecond int
+	// Restart discards the checkpoint of a previous run to the same key.
+	Restart bool
+}
+
+// keyRotationStore is the part of the profile repository a KeyRotator uses.
+type keyRotationStore interface {
+	StartKeyRotation(ctx context.Context, restart bool) (*postgres.KeyRotation, error)
+	RewrapBatch(ctx context.Context, rotation *postgres.KeyRotation, batchSize int) error
+}
+
+// skippedRetryDelay is how long a rotation waits before retrying profiles
+// that are still locked once the rest of the table is done.
+const skippedRetryDelay = time.Second
+
+// KeyRotator rewraps the encrypted columns of every profile with the primary
+// key of the keyring, so that older keys can be retired. Progress is
+// checkpointed in the database and a later run resumes where an interrupted
+// one stopped.
+type KeyRotator struct {
+	profileRepo keyRotationStore
+	opts        KeyRotationOptions
+	retryDelay  time.Duration
+	logger      *slog.Logger
+}
+
+func NewKeyRotator(profileRepo *postgres.ProfileRepository, opts KeyRotationOptions, logger *slog.Logger) (*KeyRotator, error) {
+	if opts.BatchSize <= 0 {
+		return nil, errors.New("key rotation batch size must be positive")
+	}
+	if opts.RowsPerSecond < 0 {
+		return nil, errors.New("key rotation rate must not be negative")
+	}
+
+	return &KeyRotator{
+		profileRepo: profileRepo,
+		opts:        opts,
+		retryDelay:  skippedRetryDelay,
+		logger:      logger,
+	}, nil
+}
+
+// Run rewraps profiles until the table is exhausted or ctx is cancelled and
+// returns the final checkpoint.
+func (k *KeyRotator) Run(ctx context.Context) (*postgres.KeyRotation, error) {
+	rotation, err := k.profileRepo.StartKeyRotation(ctx, k.opts.Restart)
+	if err != nil {
+		return nil, err
+	}
+	if rotation.CompletedAt != nil {
+		k.logger.Info("Key rotation already completed", "key_id", rotation.KeyID, "completed_at", rotation.CompletedAt)
+		return rotation, nil
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, k.opts.BatchSize)
+	if k.opts.RowsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(k.opts.RowsPerSecond), max(k.opts.BatchSize, k.opts.RowsPerSecond))
+	}
+
+	start := time.Now()
+	k.logger.Info("Key rotation started", "key_id", rotation.KeyID, "resume_after", rotation.LastID,
+		"scanned", rotation.Scanned, "total", rotation.Total)
+
+	for rotation.CompletedAt == nil {
+		if err := limiter.WaitN(ctx, k.opts.BatchSize); err != nil {
+			return rotation, fmt.Errorf("key rotation interrupted: %w", err)
+		}
+
+		scanned := rotation.Scanned
+		if err := k.profileRepo.RewrapBatch(ctx, rotation, k.opts.BatchSize); err != nil {
+			return rotation, err
+		}
+
+		k.logger.Info("Key rotation progress", "key_id", rotation.KeyID, "scanned", rotation.Scanned,
+			"rewrapped", rotation.Rewrapped, "skipped", len(rotation.Skipped), "total", rotation.Total,
+			"progress", rotationProgress(rotation))
+
+		if rotation.CompletedAt == nil && rotation.Scanned == scanned {
+			// Only locked profiles are left, give their writers time to finish
+			select {
+			case <-ctx.Done():
+				return rotation, fmt.Errorf("key rotation interrupted: %w", ctx.Err())
+			case <-time.After(k.retryDelay):
+			}
+		}
+	}
+
+	k.logger.Info("Key rotation finished", "key_id", rotation.KeyID, "scanned", rotation.Scanned,
+		"rewrapped", rotation.Rewrapped, "duration", time.Since(start))
+
+	return rotation, nil
+}
+
+// rotationProgress estimates progress from the row count taken when the
+// rotation started.
+func rotationProgress(rotation *postgres.KeyRotation) float64 {
+	if rotation.CompletedAt != nil || rotation.Total == 0 {
+		return 1
+	}
+	return min(float64(rotation.Scanned)/float64(rotation.Total), 0.99)
+}
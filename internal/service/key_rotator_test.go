@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeRotationStore keeps a checkpoint like the repository and records how
+// often each profile was rewrapped.
+type fakeRotationStore struct {
+	ids        []string
+	locked     map[string]bool
+	rewrapped  map[string]int
+	checkpoint *postgres.KeyRotation
+	batches    int
+	onBatch    func(batches int)
+}
+
+func newFakeRotationStore(n int) *fakeRotationStore {
+	f := &fakeRotationStore{locked: map[string]bool{}, rewrapped: map[string]int{}}
+	for i := range n {
+		f.ids = append(f.ids, fmt.Sprintf("00000000-0000-4000-8000-%012d", i))
+	}
+	return f
+}
+
+func (f *fakeRotationStore) StartKeyRotation(ctx context.Context, restart bool) (*postgres.KeyRotation, error) {
+	if f.checkpoint == nil || restart {
+		f.checkpoint = &postgres.KeyRotation{KeyID: "2026-q4", Total: int64(len(f.ids))}
+	}
+	rotation := *f.checkpoint
+	return &rotation, nil
+}
+
+func (f *fakeRotationStore) RewrapBatch(ctx context.Context, rotation *postgres.KeyRotation, batchSize int) error {
+	start, _ := slices.BinarySearch(f.ids, rotation.LastID)
+	if start < len(f.ids) && f.ids[start] == rotation.LastID {
+		start++
+	}
+	ids := slices.Clone(f.ids[start:min(start+batchSize, len(f.ids))])
+	exhausted := len(ids) < batchSize
+	if len(ids) > 0 {
+		rotation.LastID = ids[len(ids)-1]
+	}
+
+	carried := rotation.Skipped
+	if exhausted {
+		ids = append(ids, carried...)
+		carried = nil
+	}
+	skipped := []string{}
+	for _, id := range ids {
+		if f.locked[id] {
+			skipped = append(skipped, id)
+			continue
+		}
+		f.rewrapped[id]++
+		rotation.Scanned++
+	}
+	rotation.Skipped = append(skipped, carried...)
+	if exhausted && len(rotation.Skipped) == 0 {
+		now := time.Now()
+		rotation.CompletedAt = &now
+	}
+
+	checkpoint := *rotation
+	f.checkpoint = &checkpoint
+	f.batches++
+	if f.onBatch != nil {
+		f.onBatch(f.batches)
+	}
+	return nil
+}
+
+func newTestKeyRotator(store keyRotationStore) *KeyRotator {
+	return &KeyRotator{profileRepo: store, opts: KeyRotationOptions{BatchSize: 3}, retryDelay: time.Millisecond, logger: testLogger}
+}
+
+func TestKeyRotationResumesFromCheckpoint(t *testing.T) {
+	store := newFakeRotationStore(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	store.onBatch = func(batches int) {
+		if batches == 2 {
+			cancel()
+		}
+	}
+
+	rotation, err := newTestKeyRotator(store).Run(ctx)
+	if err == nil {
+		t.Fatal("Run succeeded after it was cancelled")
+	}
+	if rotation.LastID != store.ids[5] || rotation.CompletedAt != nil {
+		t.Fatalf("interrupted at %q, completed %v, want after the sixth profile", rotation.LastID, rotation.CompletedAt)
+	}
+
+	store.onBatch = nil
+	rotation, err = newTestKeyRotator(store).Run(context.Background())
+	if err != nil {
+		t.Fatalf("resumed Run: %v", err)
+	}
+	if rotation.CompletedAt == nil || rotation.Scanned != 10 {
+		t.Errorf("resumed rotation scanned %d, completed %v, want all 10 profiles", rotation.Scanned, rotation.CompletedAt)
+	}
+	for _, id := range store.ids {
+		if store.rewrapped[id] != 1 {
+			t.Errorf("profile %s rewrapped %d times, want once", id, store.rewrapped[id])
+		}
+	}
+}
+
+func TestKeyRotationRetriesLockedProfiles(t *testing.T) {
+	store := newFakeRotationStore(7)
+	store.locked[store.ids[1]] = true
+	store.onBatch = func(batches int) {
+		// The table is exhausted after the third batch, and the locked
+		// profile is released a few retries later
+		if batches == 5 {
+			delete(store.locked, store.ids[1])
+		}
+	}
+
+	rotation, err := newTestKeyRotator(store).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation.CompletedAt == nil || len(rotation.Skipped) != 0 {
+		t.Fatalf("rotation completed %v with %v skipped, want completed without skipped profiles", rotation.CompletedAt, rotation.Skipped)
+	}
+	if store.rewrapped[store.ids[1]] != 1 || store.batches != 6 {
+		t.Errorf("locked profile rewrapped %d times in %d batches, want once in the sixth batch", store.rewrapped[store.ids[1]], store.batches)
+	}
+}
@@ -4,19 +4,35 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Brrocat/user-profile-service/internal/logging"
+	"github.com/Brrocat/user-profile-service/internal/metrics"
 	"github.com/Brrocat/user-profile-service/internal/models"
 	"github.com/Brrocat/user-profile-service/internal/repository/postgres"
 	"github.com/Brrocat/user-profile-service/internal/repository/redis"
 	"github.com/Brrocat/user-profile-service/pkg/validation"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
+	"slices"
+	"strings"
 )
 
 var (
 	ErrProfileNotFound      = errors.New("profile not found")
 	ErrProfileAlreadyExists = errors.New("profile already exists")
 	ErrInvalidData          = errors.New("invalid data")
+	// ErrLookupUnavailable means lookups by phone or license are not
+	// configured, because the keyring has no blind index key.
+	ErrLookupUnavailable = errors.New("lookup by phone or license is not configured")
+	// ErrAmbiguousLookup means several profiles share the phone or license
+	// number, so the lookup cannot tell which one was meant.
+	ErrAmbiguousLookup = errors.New("several profiles match the lookup")
 )
 
+var tracer = otel.Tracer("github.com/Brrocat/user-profile-service/internal/service")
+
 type ProfileService struct {
 	profileRepo *postgres.ProfileRepository
 	cacheRepo   *redis.CacheRepository
@@ -38,160 +54,250 @@ func NewProfileService(
 	}
 }
 
-func (s *ProfileService) GetUserProfile(ctx context.Context, userID string) (*models.UserProfile, error) {
-	s.logger.Debug("Getting user profile", "user_id", userID)
+// log returns the request-scoped logger from ctx, falling back to the
+// service logger for background callers.
+func (s *ProfileService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+func (s *ProfileService) GetUserProfile(ctx context.Context, userID string) (_ *models.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.GetUserProfile", trace.WithAttributes(attribute.String("user_id", userID)))
+	defer func() { endSpan(span, err) }()
+
+	s.log(ctx).Debug("Getting user profile", "user_id", userID)
 
 	// Try to get from cache first
 	cachedProfile, err := s.cacheRepo.GetCachedProfile(ctx, userID)
+	if errors.Is(err, redis.ErrNegativeHit) {
+		s.log(ctx).Debug("Profile cached as not found", "user_id", userID)
+		return nil, ErrProfileNotFound
+	}
 	if err != nil {
-		s.logger.Warn("Failed to get profile from cache", "user_id", userID, "error", err)
+		s.log(ctx).Warn("Failed to get profile from cache", "user_id", userID, "error", err)
 		// Continue to database lookup
 	}
 
+	span.SetAttributes(attribute.Bool("cache_hit", cachedProfile != nil))
 	if cachedProfile != nil {
-		s.logger.Debug("Profile found in cache", "user_id", userID)
+		s.log(ctx).Debug("Profile found in cache", "user_id", userID)
 		return cachedProfile, nil
 	}
 
 	// Get from database
 	profile, err := s.profileRepo.GetProfileByUserID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get profile from database", "user_id", userID, "error", err)
+		s.log(ctx).Error("Failed to get profile from database", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("failed to get profile: %w", err)
 	}
 
 	if profile == nil {
-		s.logger.Debug("Profile not found", "user_id", userID)
+		s.log(ctx).Debug("Profile not found", "user_id", userID)
+		if err := s.cacheRepo.CacheNotFound(ctx, userID); err != nil {
+			s.log(ctx).Warn("Failed to cache missing profile", "user_id", userID, "error", err)
+		}
 		return nil, ErrProfileNotFound
 	}
 
 	// Cache the profile for future requests
 	if err := s.cacheRepo.CacheProfile(ctx, profile); err != nil {
-		s.logger.Warn("Failed to cache profile", "user_id", userID, "error", err)
+		s.log(ctx).Warn("Failed to cache profile", "user_id", userID, "error", err)
 		// Non-critical error, continue
 	}
 
-	s.logger.Debug("Profile retrieved from database", "user_id", userID)
+	s.log(ctx).Debug("Profile retrieved from database", "user_id", userID)
 	return profile, nil
 }
 
-func (s *ProfileService) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (*models.UserProfile, error) {
-	s.logger.Debug("Creating user profile", "user_id", req.UserID)
+func (s *ProfileService) CreateUserProfile(ctx context.Context, req *models.CreateProfileRequest) (_ *models.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.CreateUserProfile", trace.WithAttributes(attribute.String("user_id", req.UserID)))
+	defer func() { endSpan(span, err) }()
+
+	s.log(ctx).Debug("Creating user profile", "user_id", req.UserID)
 
 	// Validate input
 	if err := s.validator.ValidateStruct(req); err != nil {
 		validationErrors := s.validator.FormatValidationErrors(err)
-		s.logger.Warn("Validation failed for create profile", "user_id", req.UserID, "errors", validationErrors)
+		s.log(ctx).Warn("Validation failed for create profile", "user_id", req.UserID, "errors", validationErrors)
 		return nil, fmt.Errorf("%w: %v", ErrInvalidData, validationErrors)
 	}
 
 	// Check if profile already exists
 	existingProfile, err := s.profileRepo.GetProfileByUserID(ctx, req.UserID)
 	if err != nil {
-		s.logger.Error("Failed to check existing profile", "user_id", req.UserID, "error", err)
+		s.log(ctx).Error("Failed to check existing profile", "user_id", req.UserID, "error", err)
 		return nil, fmt.Errorf("failed to check existing profile: %w", err)
 	}
 
 	if existingProfile != nil {
-		s.logger.Warn("Profile already exists", "user_id", req.UserID)
+		s.log(ctx).Warn("Profile already exists", "user_id", req.UserID)
 		return nil, ErrProfileAlreadyExists
 	}
 
 	// Create profile
 	profile, err := s.profileRepo.CreateProfile(ctx, req)
 	if err != nil {
-		s.logger.Error("Failed to create profile", "user_id", req.UserID, "error", err)
+		s.log(ctx).Error("Failed to create profile", "user_id", req.UserID, "error", err)
 		return nil, fmt.Errorf("failed to create profile: %w", err)
 	}
 
 	// Cache the new profile
 	if err := s.cacheRepo.CacheProfile(ctx, profile); err != nil {
-		s.logger.Warn("Failed to cache new profile", "user_id", req.UserID, "error", err)
+		s.log(ctx).Warn("Failed to cache new profile", "user_id", req.UserID, "error", err)
 		// Non-critical error, continue
 	}
 
-	s.logger.Info("Profile created successfully", "user_id", req.UserID, "profile_id", profile.ID)
+	metrics.ProfilesCreated.Inc()
+	s.log(ctx).Info("Profile created successfully", "user_id", req.UserID, "profile_id", profile.ID)
 	return profile, nil
 }
 
-func (s *ProfileService) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (*models.UserProfile, error) {
-	s.logger.Debug("Updating user profile", "user_id", userID)
+func (s *ProfileService) UpdateUserProfile(ctx context.Context, userID string, req *models.UpdateProfileRequest) (_ *models.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.UpdateUserProfile", trace.WithAttributes(attribute.String("user_id", userID)))
+	defer func() { endSpan(span, err) }()
+
+	s.log(ctx).Debug("Updating user profile", "user_id", userID)
 
 	// Validate input
 	if err := s.validator.ValidateStruct(req); err != nil {
 		validationErrors := s.validator.FormatValidationErrors(err)
-		s.logger.Warn("Validation failed for update profile", "user_id", userID, "errors", validationErrors)
+		s.log(ctx).Warn("Validation failed for update profile", "user_id", userID, "errors", validationErrors)
 		return nil, fmt.Errorf("%w: %v", ErrInvalidData, validationErrors)
 	}
 
 	// Check if profile exists
 	existingProfile, err := s.profileRepo.GetProfileByUserID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get existing profile for update", "user_id", userID, "error", err)
+		s.log(ctx).Error("Failed to get existing profile for update", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("failed to get existing profile: %w", err)
 	}
 
 	if existingProfile == nil {
-		s.logger.Warn("Profile not found for update", "user_id", userID)
+		s.log(ctx).Warn("Profile not found for update", "user_id", userID)
 		return nil, ErrProfileNotFound
 	}
 
 	// Update profile
 	updatedProfile, err := s.profileRepo.UpdateProfile(ctx, userID, req)
 	if err != nil {
-		s.logger.Error("Failed to update profile", "user_id", userID, "error", err)
+		s.log(ctx).Error("Failed to update profile", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 
 	if updatedProfile == nil {
-		s.logger.Error("Profile update returned nil", "user_id", userID)
+		s.log(ctx).Error("Profile update returned nil", "user_id", userID)
 		return nil, fmt.Errorf("profile update failed")
 	}
 
 	// Update cache
 	if err := s.cacheRepo.CacheProfile(ctx, updatedProfile); err != nil {
-		s.logger.Warn("Failed to update cached profile", "user_id", userID, "error", err)
+		s.log(ctx).Warn("Failed to update cached profile", "user_id", userID, "error", err)
 		// Non-critical error, continue
 	}
 
-	s.logger.Info("Profile updated successfully", "user_id", userID, "profile_id", updatedProfile.ID)
+	metrics.ProfilesUpdated.Inc()
+	s.log(ctx).Info("Profile updated successfully", "user_id", userID, "profile_id", updatedProfile.ID)
 	return updatedProfile, nil
 }
 
-func (s *ProfileService) DeleteUserProfile(ctx context.Context, userID string) error {
-	s.logger.Debug("Deleting user profile", "user_id", userID)
+func (s *ProfileService) DeleteUserProfile(ctx context.Context, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.DeleteUserProfile", trace.WithAttributes(attribute.String("user_id", userID)))
+	defer func() { endSpan(span, err) }()
+
+	s.log(ctx).Debug("Deleting user profile", "user_id", userID)
 
 	// Check if profile exists
 	existingProfile, err := s.profileRepo.GetProfileByUserID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to get existing profile for deletion", "user_id", userID, "error", err)
+		s.log(ctx).Error("Failed to get existing profile for deletion", "user_id", userID, "error", err)
 		return fmt.Errorf("failed to get existing profile: %w", err)
 	}
 
 	if existingProfile == nil {
-		s.logger.Warn("Profile not found for deletion", "user_id", userID)
+		s.log(ctx).Warn("Profile not found for deletion", "user_id", userID)
 		return ErrProfileNotFound
 	}
 
 	// Delete from database
 	err = s.profileRepo.DeleteProfile(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to delete profile", "user_id", userID, "error", err)
+		s.log(ctx).Error("Failed to delete profile", "user_id", userID, "error", err)
 		return fmt.Errorf("failed to delete profile: %w", err)
 	}
 
 	// Delete from cache
 	if err := s.cacheRepo.DeleteCachedProfile(ctx, userID); err != nil {
-		s.logger.Warn("Failed to delete cached profile", "user_id", userID, "error", err)
+		s.log(ctx).Warn("Failed to delete cached profile", "user_id", userID, "error", err)
 		// Non-critical error, continue
 	}
 
-	s.logger.Info("Profile deleted successfully", "user_id", userID)
+	metrics.ProfilesDeleted.Inc()
+	s.log(ctx).Info("Profile deleted successfully", "user_id", userID)
 	return nil
 }
 
+// FindProfileByPhone returns the profile whose phone number matches phone
+// after normalization.
+func (s *ProfileService) FindProfileByPhone(ctx context.Context, phone, ownerID string) (_ *models.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.FindProfileByPhone")
+	defer func() { endSpan(span, err) }()
+
+	return s.findProfile(ctx, "phone", phone, ownerID, s.profileRepo.FindProfilesByPhone)
+}
+
+// FindProfileByLicense returns the profile whose driving license number
+// matches license after normalization.
+func (s *ProfileService) FindProfileByLicense(ctx context.Context, license, ownerID string) (_ *models.UserProfile, err error) {
+	ctx, span := tracer.Start(ctx, "ProfileService.FindProfileByLicense")
+	defer func() { endSpan(span, err) }()
+
+	return s.findProfile(ctx, "driving_license", license, ownerID, s.profileRepo.FindProfilesByLicense)
+}
+
+// findProfile runs a blind index lookup and fails with ErrAmbiguousLookup
+// when more than one profile matches. The searched value is personal data and
+// is never logged.
+func (s *ProfileService) findProfile(
+	ctx context.Context,
+	field, value, ownerID string,
+	find func(context.Context, string) ([]*models.UserProfile, error),
+) (*models.UserProfile, error) {
+	s.log(ctx).Debug("Finding user profile", "field", field)
+
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("%w: %s is required", ErrInvalidData, field)
+	}
+
+	profiles, err := find(ctx, value)
+	if errors.Is(err, postgres.ErrNoBlindIndex) {
+		return nil, ErrLookupUnavailable
+	}
+	if err != nil {
+		s.log(ctx).Error("Failed to find profile", "field", field, "error", err)
+		return nil, fmt.Errorf("failed to find profile: %w", err)
+	}
+
+	if ownerID != "" {
+		profiles = slices.DeleteFunc(profiles, func(p *models.UserProfile) bool {
+			return p.UserID != ownerID
+		})
+	}
+	if len(profiles) == 0 {
+		s.log(ctx).Debug("No profile matches lookup", "field", field)
+		return nil, ErrProfileNotFound
+	}
+	if len(profiles) > 1 {
+		s.log(ctx).Warn("Several profiles match lookup", "field", field, "matches", len(profiles))
+		return nil, ErrAmbiguousLookup
+	}
+
+	return profiles[0], nil
+}
+
 func (s *ProfileService) GetMultipleProfiles(ctx context.Context, userIDs []string) ([]*models.UserProfile, error) {
-	s.logger.Debug("Getting multiple profiles", "user_ids", userIDs)
+	ctx, span := tracer.Start(ctx, "ProfileService.GetMultipleProfiles", trace.WithAttributes(attribute.Int("user_count", len(userIDs))))
+	defer span.End()
+
+	s.log(ctx).Debug("Getting multiple profiles", "user_ids", userIDs)
 
 	profiles := make([]*models.UserProfile, 0, len(userIDs))
 	missingFromCache := make([]string, 0)
@@ -199,8 +305,11 @@ func (s *ProfileService) GetMultipleProfiles(ctx context.Context, userIDs []stri
 	// Try to get from cache first
 	for _, userID := range userIDs {
 		cachedProfile, err := s.cacheRepo.GetCachedProfile(ctx, userID)
+		if errors.Is(err, redis.ErrNegativeHit) {
+			continue
+		}
 		if err != nil {
-			s.logger.Warn("Failed to get profile from cache", "user_id", userID, "error", err)
+			s.log(ctx).Warn("Failed to get profile from cache", "user_id", userID, "error", err)
 			missingFromCache = append(missingFromCache, userID)
 			continue
 		}
@@ -212,6 +321,8 @@ func (s *ProfileService) GetMultipleProfiles(ctx context.Context, userIDs []stri
 		}
 	}
 
+	span.SetAttributes(attribute.Int("cache_misses", len(missingFromCache)))
+
 	// If all profiles were in cache, return them
 	if len(missingFromCache) == 0 {
 		return profiles, nil
@@ -221,7 +332,7 @@ func (s *ProfileService) GetMultipleProfiles(ctx context.Context, userIDs []stri
 	for _, userID := range missingFromCache {
 		profile, err := s.profileRepo.GetProfileByUserID(ctx, userID)
 		if err != nil {
-			s.logger.Error("Failed to get profile from database", "user_id", userID, "error", err)
+			s.log(ctx).Error("Failed to get profile from database", "user_id", userID, "error", err)
 			continue
 		}
 
@@ -229,10 +340,22 @@ func (s *ProfileService) GetMultipleProfiles(ctx context.Context, userIDs []stri
 			profiles = append(profiles, profile)
 			// Cache the profile for future requests
 			if err := s.cacheRepo.CacheProfile(ctx, profile); err != nil {
-				s.logger.Warn("Failed to cache profile", "user_id", userID, "error", err)
+				s.log(ctx).Warn("Failed to cache profile", "user_id", userID, "error", err)
 			}
 		}
 	}
 
 	return profiles, nil
 }
+
+// endSpan records unexpected errors on span and ends it. Domain errors such as
+// a missing profile are expected outcomes and leave the span status unset.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrProfileNotFound) &&
+		!errors.Is(err, ErrProfileAlreadyExists) && !errors.Is(err, ErrInvalidData) &&
+		!errors.Is(err, ErrLookupUnavailable) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
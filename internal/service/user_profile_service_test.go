@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Brrocat/user-profile-service/internal/models"
+	"testing"
+)
+
+func TestFindProfileRejectsAmbiguousMatches(t *testing.T) {
+	s := &ProfileService{logger: testLogger}
+	owners := []string{"user-2", "user-1"}
+	find := func(ctx context.Context, value string) ([]*models.UserProfile, error) {
+		var matches []*models.UserProfile
+		for _, owner := range owners {
+			matches = append(matches, &models.UserProfile{UserID: owner, Phone: value})
+		}
+		return matches, nil
+	}
+	ctx := context.Background()
+
+	if _, err := s.findProfile(ctx, "phone", "+48123456789", "", find); !errors.Is(err, ErrAmbiguousLookup) {
+		t.Errorf("findProfile with two matches = %v, want ErrAmbiguousLookup", err)
+	}
+
+	// Limited to one owner, the other match does not count
+	profile, err := s.findProfile(ctx, "phone", "+48123456789", "user-1", find)
+	if err != nil || profile.UserID != "user-1" {
+		t.Errorf("findProfile limited to user-1 = %v, %v, want user-1", profile, err)
+	}
+
+	owners = owners[:1]
+	profile, err = s.findProfile(ctx, "phone", "+48123456789", "", find)
+	if err != nil || profile.UserID != "user-2" {
+		t.Errorf("findProfile with one match = %v, %v, want user-2", profile, err)
+	}
+}
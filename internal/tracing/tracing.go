@@ -0,0 +1,97 @@
+// Package tracing configures the OpenTelemetry tracer provider and the W3C
+// propagators used by the server.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"io"
+	"os"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Options selects where spans are exported.
+type Options struct {
+	ServiceName string
+	Exporter    string
+	// OutputFile receives spans from the stdout exporter instead of standard
+	// output when set.
+	OutputFile string
+}
+
+// Setup installs the global tracer provider and propagators. The returned
+// function flushes pending spans and must be called on shutdown.
+//
+// The OTLP exporter and the sampler are configured through the standard
+// OTEL_EXPORTER_OTLP_* and OTEL_TRACES_SAMPLER* environment variables.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	// Always propagate W3C trace context, even when spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+
+	case ExporterStdout:
+		var w io.Writer = os.Stdout
+		if opts.OutputFile != "" {
+			f, err := os.OpenFile(opts.OutputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open trace output file: %w", err)
+			}
+			w, closer = f, f
+		}
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(w)); err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+
+	case ExporterOTLP:
+		var err error
+		if exporter, err = otlptracegrpc.New(ctx); err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,22 @@
+-- Repair the table created by 001
+
+-- 001 set NEW.update_at, so every update of a profile failed
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+    RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DO $$
+BEGIN
+    -- 001 created the column as driving_licence while the service queries driving_license
+    IF EXISTS (SELECT 1
+               FROM information_schema.columns
+               WHERE table_name = 'user_profiles'
+                 AND column_name = 'driving_licence') THEN
+        ALTER TABLE user_profiles RENAME COLUMN driving_licence TO driving_license;
+    END IF;
+END
+$$;
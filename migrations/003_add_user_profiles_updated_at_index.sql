@@ -0,0 +1,2 @@
+-- Support keyset pagination over recently updated profiles (cache warm-up)
+CREATE INDEX IF NOT EXISTS idx_user_profile_updated_at ON user_profiles(updated_at DESC, user_id DESC);
@@ -0,0 +1,10 @@
+-- Store personal data as encrypted envelopes (base64). Bit i of
+-- encrypted_columns is set when the i-th of phone, date_of_birth, address and
+-- driving_license holds an envelope rather than plaintext written before
+-- encryption was enabled.
+ALTER TABLE user_profiles
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN date_of_birth TYPE TEXT USING date_of_birth::text,
+    ALTER COLUMN address TYPE TEXT,
+    ALTER COLUMN driving_license TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS encrypted_columns SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Checkpoints of re-encryption jobs, one row per target key ID
+CREATE TABLE IF NOT EXISTS encryption_key_rotations
+(
+    key_id       TEXT PRIMARY KEY,
+    last_id      UUID,
+    scanned      BIGINT                   NOT NULL DEFAULT 0,
+    rewrapped    BIGINT                   NOT NULL DEFAULT 0,
+    total        BIGINT                   NOT NULL DEFAULT 0,
+    started_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
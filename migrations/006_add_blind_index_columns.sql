@@ -0,0 +1,7 @@
+-- Blind indexes (HMAC of the normalized value) for lookups by encrypted phone and license
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS phone_index           TEXT,
+    ADD COLUMN IF NOT EXISTS driving_license_index TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_user_profile_phone_index ON user_profiles(phone_index);
+CREATE INDEX IF NOT EXISTS idx_user_profile_driving_license_index ON user_profiles(driving_license_index);
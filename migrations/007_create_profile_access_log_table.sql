@@ -0,0 +1,39 @@
+-- Append-only audit log of reads of personal data
+CREATE TABLE IF NOT EXISTS profile_access_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    accessed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    caller      TEXT                     NOT NULL,
+    roles       TEXT[]                   NOT NULL DEFAULT '{}',
+    method      TEXT                     NOT NULL,
+    user_id     UUID                     NOT NULL,
+    purpose     TEXT                     NOT NULL DEFAULT '',
+    fields      TEXT[]                   NOT NULL DEFAULT '{}',
+    request_id  TEXT                     NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_access_log_user_id ON profile_access_log(user_id, accessed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_profile_access_log_caller ON profile_access_log(caller, accessed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_profile_access_log_accessed_at ON profile_access_log(accessed_at);
+
+-- Records cannot be changed, and only the retention purge, which sets
+-- profile_access_log.purge for its transaction, may delete them
+CREATE OR REPLACE FUNCTION profile_access_log_append_only()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('profile_access_log.purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'profile_access_log is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER profile_access_log_append_only
+    BEFORE UPDATE OR DELETE ON profile_access_log
+    FOR EACH ROW
+    EXECUTE FUNCTION profile_access_log_append_only();
+
+CREATE OR REPLACE TRIGGER profile_access_log_no_truncate
+    BEFORE TRUNCATE ON profile_access_log
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION profile_access_log_append_only();
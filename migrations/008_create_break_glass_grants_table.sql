@@ -0,0 +1,21 @@
+-- Short-lived grants to read every field of one profile, requested with a
+-- ticket reference and reason
+CREATE TABLE IF NOT EXISTS break_glass_grants
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    subject    TEXT                     NOT NULL,
+    user_id    UUID                     NOT NULL,
+    ticket     TEXT                     NOT NULL,
+    reason     TEXT                     NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_break_glass_grants_subject ON break_glass_grants(subject, created_at DESC);
+
+-- Reads under a break-glass grant are flagged in the access log
+ALTER TABLE profile_access_log
+    ADD COLUMN IF NOT EXISTS break_glass_grant_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_profile_access_log_break_glass ON profile_access_log(break_glass_grant_id)
+    WHERE break_glass_grant_id IS NOT NULL;
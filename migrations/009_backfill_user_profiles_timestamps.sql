@@ -0,0 +1,15 @@
+-- Rows with a NULL updated_at never match the warm-up's keyset pagination, so
+-- backfill the timestamps and forbid NULLs from now on. The updated_at trigger
+-- is disabled so the backfill keeps the creation time instead of NOW().
+ALTER TABLE user_profiles DISABLE TRIGGER update_user_profiles_updated_at;
+
+UPDATE user_profiles
+SET created_at = COALESCE(created_at, updated_at, NOW()),
+    updated_at = COALESCE(updated_at, created_at, NOW())
+WHERE created_at IS NULL OR updated_at IS NULL;
+
+ALTER TABLE user_profiles ENABLE TRIGGER update_user_profiles_updated_at;
+
+ALTER TABLE user_profiles
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
@@ -0,0 +1,6 @@
+-- Grants can be revoked before they expire
+ALTER TABLE break_glass_grants
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+
+-- Expired grants are purged with the access log
+CREATE INDEX IF NOT EXISTS idx_break_glass_grants_expires_at ON break_glass_grants(expires_at);
@@ -0,0 +1,4 @@
+-- Profiles a key rotation skipped because another transaction held their lock,
+-- retried once the rotation reaches the end of the table
+ALTER TABLE encryption_key_rotations
+    ADD COLUMN IF NOT EXISTS skipped UUID[] NOT NULL DEFAULT '{}';
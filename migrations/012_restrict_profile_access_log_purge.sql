@@ -0,0 +1,20 @@
+-- The purge setting alone let any session delete any record. The purge now
+-- also skips records younger than the minimum retention, which matches
+-- config.MinAuditRetention and can only be lowered by replacing this function
+CREATE OR REPLACE FUNCTION profile_access_log_min_retention()
+    RETURNS INTERVAL AS $$
+SELECT INTERVAL '30 days';
+$$ language 'sql' IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION profile_access_log_append_only()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('profile_access_log.purge', true) = 'on' THEN
+        IF OLD.accessed_at < NOW() - profile_access_log_min_retention() THEN
+            RETURN OLD;
+        END IF;
+        RETURN NULL;
+    END IF;
+    RAISE EXCEPTION 'profile_access_log is append-only';
+END;
+$$ language 'plpgsql';
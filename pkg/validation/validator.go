@@ -3,7 +3,9 @@ package validation
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -18,6 +20,7 @@ func NewValidator() *Validator {
 	// Register custom validations
 	v.RegisterValidation("date", validateDate)
 	v.RegisterValidation("phone", validatePhone)
+	v.RegisterValidation("nocontrol", validateNoControl)
 
 	return &Validator{validate: v}
 }
@@ -48,6 +51,12 @@ func validatePhone(fl validator.FieldLevel) bool {
 	return matched
 }
 
+// validateNoControl rejects control characters, which have no place in
+// profile text.
+func validateNoControl(fl validator.FieldLevel) bool {
+	return !strings.ContainsFunc(fl.Field().String(), unicode.IsControl)
+}
+
 func (v *Validator) FormatValidationErrors(err error) map[string]string {
 	errors := make(map[string]string)
 
@@ -65,6 +74,8 @@ func (v *Validator) FormatValidationErrors(err error) map[string]string {
 				errors[field] = fmt.Sprintf("%s must be a valid date in YYYY-MM-DD format", field)
 			case "phone":
 				errors[field] = fmt.Sprintf("%s must be a valid phone number", field)
+			case "nocontrol":
+				errors[field] = fmt.Sprintf("%s must not contain control characters", field)
 			default:
 				errors[field] = fmt.Sprintf("%s failed %s validation", field, tag)
 			}
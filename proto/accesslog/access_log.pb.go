@@ -0,0 +1,382 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: accesslog/access_log.proto
+
+package accesslog
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// QueryAccessLogRequest selects access records. Unset fields match
+// everything.
+type QueryAccessLogRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Caller string                 `protobuf:"bytes,2,opt,name=caller,proto3" json:"caller,omitempty"`
+	Since  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	Until  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"`
+	// Selects only reads under a break-glass grant.
+	BreakGlass bool `protobuf:"varint,5,opt,name=break_glass,json=breakGlass,proto3" json:"break_glass,omitempty"`
+	// Continues from next_before_id of a previous page.
+	BeforeId int64 `protobuf:"varint,6,opt,name=before_id,json=beforeId,proto3" json:"before_id,omitempty"`
+	// Defaults to 100, at most 1000.
+	Limit         int32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryAccessLogRequest) Reset() {
+	*x = QueryAccessLogRequest{}
+	mi := &file_accesslog_access_log_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryAccessLogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryAccessLogRequest) ProtoMessage() {}
+
+func (x *QueryAccessLogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_accesslog_access_log_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryAccessLogRequest.ProtoReflect.Descriptor instead.
+func (*QueryAccessLogRequest) Descriptor() ([]byte, []int) {
+	return file_accesslog_access_log_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryAccessLogRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *QueryAccessLogRequest) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *QueryAccessLogRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *QueryAccessLogRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *QueryAccessLogRequest) GetBreakGlass() bool {
+	if x != nil {
+		return x.BreakGlass
+	}
+	return false
+}
+
+func (x *QueryAccessLogRequest) GetBeforeId() int64 {
+	if x != nil {
+		return x.BeforeId
+	}
+	return 0
+}
+
+func (x *QueryAccessLogRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type QueryAccessLogResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Records []*AccessRecord        `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	// Omitted on the last page.
+	NextBeforeId  int64 `protobuf:"varint,2,opt,name=next_before_id,json=nextBeforeId,proto3" json:"next_before_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryAccessLogResponse) Reset() {
+	*x = QueryAccessLogResponse{}
+	mi := &file_accesslog_access_log_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryAccessLogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryAccessLogResponse) ProtoMessage() {}
+
+func (x *QueryAccessLogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_accesslog_access_log_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryAccessLogResponse.ProtoReflect.Descriptor instead.
+func (*QueryAccessLogResponse) Descriptor() ([]byte, []int) {
+	return file_accesslog_access_log_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *QueryAccessLogResponse) GetRecords() []*AccessRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *QueryAccessLogResponse) GetNextBeforeId() int64 {
+	if x != nil {
+		return x.NextBeforeId
+	}
+	return 0
+}
+
+// AccessRecord names the profile fields that were returned, never their
+// values.
+type AccessRecord struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	AccessedAt        *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=accessed_at,json=accessedAt,proto3" json:"accessed_at,omitempty"`
+	Caller            string                 `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"`
+	Roles             []string               `protobuf:"bytes,4,rep,name=roles,proto3" json:"roles,omitempty"`
+	Method            string                 `protobuf:"bytes,5,opt,name=method,proto3" json:"method,omitempty"`
+	UserId            string                 `protobuf:"bytes,6,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Purpose           string                 `protobuf:"bytes,7,opt,name=purpose,proto3" json:"purpose,omitempty"`
+	Fields            []string               `protobuf:"bytes,8,rep,name=fields,proto3" json:"fields,omitempty"`
+	RequestId         string                 `protobuf:"bytes,9,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	BreakGlassGrantId string                 `protobuf:"bytes,10,opt,name=break_glass_grant_id,json=breakGlassGrantId,proto3" json:"break_glass_grant_id,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AccessRecord) Reset() {
+	*x = AccessRecord{}
+	mi := &file_accesslog_access_log_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccessRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessRecord) ProtoMessage() {}
+
+func (x *AccessRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_accesslog_access_log_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessRecord.ProtoReflect.Descriptor instead.
+func (*AccessRecord) Descriptor() ([]byte, []int) {
+	return file_accesslog_access_log_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AccessRecord) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AccessRecord) GetAccessedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AccessedAt
+	}
+	return nil
+}
+
+func (x *AccessRecord) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *AccessRecord) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *AccessRecord) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *AccessRecord) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AccessRecord) GetPurpose() string {
+	if x != nil {
+		return x.Purpose
+	}
+	return ""
+}
+
+func (x *AccessRecord) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *AccessRecord) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *AccessRecord) GetBreakGlassGrantId() string {
+	if x != nil {
+		return x.BreakGlassGrantId
+	}
+	return ""
+}
+
+var File_accesslog_access_log_proto protoreflect.FileDescriptor
+
+const file_accesslog_access_log_proto_rawDesc = "" +
+	"\n" +
+	"\x1aaccesslog/access_log.proto\x12\taccesslog\x1a\x1fgoogle/protobuf/timestamp.proto\"\x80\x02\n" +
+	"\x15QueryAccessLogRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06caller\x18\x02 \x01(\tR\x06caller\x120\n" +
+	"\x05since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x1f\n" +
+	"\vbreak_glass\x18\x05 \x01(\bR\n" +
+	"breakGlass\x12\x1b\n" +
+	"\tbefore_id\x18\x06 \x01(\x03R\bbeforeId\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x05R\x05limit\"q\n" +
+	"\x16QueryAccessLogResponse\x121\n" +
+	"\arecords\x18\x01 \x03(\v2\x17.accesslog.AccessRecordR\arecords\x12$\n" +
+	"\x0enext_before_id\x18\x02 \x01(\x03R\fnextBeforeId\"\xbc\x02\n" +
+	"\fAccessRecord\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12;\n" +
+	"\vaccessed_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"accessedAt\x12\x16\n" +
+	"\x06caller\x18\x03 \x01(\tR\x06caller\x12\x14\n" +
+	"\x05roles\x18\x04 \x03(\tR\x05roles\x12\x16\n" +
+	"\x06method\x18\x05 \x01(\tR\x06method\x12\x17\n" +
+	"\auser_id\x18\x06 \x01(\tR\x06userId\x12\x18\n" +
+	"\apurpose\x18\a \x01(\tR\apurpose\x12\x16\n" +
+	"\x06fields\x18\b \x03(\tR\x06fields\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\t \x01(\tR\trequestId\x12/\n" +
+	"\x14break_glass_grant_id\x18\n" +
+	" \x01(\tR\x11breakGlassGrantId2i\n" +
+	"\x10AccessLogService\x12U\n" +
+	"\x0eQueryAccessLog\x12 .accesslog.QueryAccessLogRequest\x1a!.accesslog.QueryAccessLogResponseB9Z7github.com/Brrocat/user-profile-service/proto/accesslogb\x06proto3"
+
+var (
+	file_accesslog_access_log_proto_rawDescOnce sync.Once
+	file_accesslog_access_log_proto_rawDescData []byte
+)
+
+func file_accesslog_access_log_proto_rawDescGZIP() []byte {
+	file_accesslog_access_log_proto_rawDescOnce.Do(func() {
+		file_accesslog_access_log_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_accesslog_access_log_proto_rawDesc), len(file_accesslog_access_log_proto_rawDesc)))
+	})
+	return file_accesslog_access_log_proto_rawDescData
+}
+
+var file_accesslog_access_log_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_accesslog_access_log_proto_goTypes = []any{
+	(*QueryAccessLogRequest)(nil),  // 0: accesslog.QueryAccessLogRequest
+	(*QueryAccessLogResponse)(nil), // 1: accesslog.QueryAccessLogResponse
+	(*AccessRecord)(nil),           // 2: accesslog.AccessRecord
+	(*timestamppb.Timestamp)(nil),  // 3: google.protobuf.Timestamp
+}
+var file_accesslog_access_log_proto_depIdxs = []int32{
+	3, // 0: accesslog.QueryAccessLogRequest.since:type_name -> google.protobuf.Timestamp
+	3, // 1: accesslog.QueryAccessLogRequest.until:type_name -> google.protobuf.Timestamp
+	2, // 2: accesslog.QueryAccessLogResponse.records:type_name -> accesslog.AccessRecord
+	3, // 3: accesslog.AccessRecord.accessed_at:type_name -> google.protobuf.Timestamp
+	0, // 4: accesslog.AccessLogService.QueryAccessLog:input_type -> accesslog.QueryAccessLogRequest
+	1, // 5: accesslog.AccessLogService.QueryAccessLog:output_type -> accesslog.QueryAccessLogResponse
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_accesslog_access_log_proto_init() }
+func file_accesslog_access_log_proto_init() {
+	if File_accesslog_access_log_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_accesslog_access_log_proto_rawDesc), len(file_accesslog_access_log_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_accesslog_access_log_proto_goTypes,
+		DependencyIndexes: file_accesslog_access_log_proto_depIdxs,
+		MessageInfos:      file_accesslog_access_log_proto_msgTypes,
+	}.Build()
+	File_accesslog_access_log_proto = out.File
+	file_accesslog_access_log_proto_goTypes = nil
+	file_accesslog_access_log_proto_depIdxs = nil
+}
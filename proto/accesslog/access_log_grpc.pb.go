@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             (unknown)
+// source: accesslog/access_log.proto
+
+package accesslog
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AccessLogService_QueryAccessLog_FullMethodName = "/accesslog.AccessLogService/QueryAccessLog"
+)
+
+// AccessLogServiceClient is the client API for AccessLogService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AccessLogService lists reads of personal data, newest first. Callers
+// limited to their own profile must set user_id to their own user ID.
+type AccessLogServiceClient interface {
+	QueryAccessLog(ctx context.Context, in *QueryAccessLogRequest, opts ...grpc.CallOption) (*QueryAccessLogResponse, error)
+}
+
+type accessLogServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAccessLogServiceClient(cc grpc.ClientConnInterface) AccessLogServiceClient {
+	return &accessLogServiceClient{cc}
+}
+
+func (c *accessLogServiceClient) QueryAccessLog(ctx context.Context, in *QueryAccessLogRequest, opts ...grpc.CallOption) (*QueryAccessLogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryAccessLogResponse)
+	err := c.cc.Invoke(ctx, AccessLogService_QueryAccessLog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AccessLogServiceServer is the server API for AccessLogService service.
+// All implementations must embed UnimplementedAccessLogServiceServer
+// for forward compatibility.
+//
+// AccessLogService lists reads of personal data, newest first. Callers
+// limited to their own profile must set user_id to their own user ID.
+type AccessLogServiceServer interface {
+	QueryAccessLog(context.Context, *QueryAccessLogRequest) (*QueryAccessLogResponse, error)
+	mustEmbedUnimplementedAccessLogServiceServer()
+}
+
+// UnimplementedAccessLogServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAccessLogServiceServer struct{}
+
+func (UnimplementedAccessLogServiceServer) QueryAccessLog(context.Context, *QueryAccessLogRequest) (*QueryAccessLogResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method QueryAccessLog not implemented")
+}
+func (UnimplementedAccessLogServiceServer) mustEmbedUnimplementedAccessLogServiceServer() {}
+func (UnimplementedAccessLogServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeAccessLogServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AccessLogServiceServer will
+// result in compilation errors.
+type UnsafeAccessLogServiceServer interface {
+	mustEmbedUnimplementedAccessLogServiceServer()
+}
+
+func RegisterAccessLogServiceServer(s grpc.ServiceRegistrar, srv AccessLogServiceServer) {
+	// If the following call panics, it indicates UnimplementedAccessLogServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AccessLogService_ServiceDesc, srv)
+}
+
+func _AccessLogService_QueryAccessLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryAccessLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AccessLogServiceServer).QueryAccessLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AccessLogService_QueryAccessLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AccessLogServiceServer).QueryAccessLog(ctx, req.(*QueryAccessLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AccessLogService_ServiceDesc is the grpc.ServiceDesc for AccessLogService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AccessLogService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "accesslog.AccessLogService",
+	HandlerType: (*AccessLogServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryAccessLog",
+			Handler:    _AccessLogService_QueryAccessLog_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "accesslog/access_log.proto",
+}
@@ -0,0 +1,226 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: profilelookup/profile_lookup.proto
+
+package profilelookup
+
+import (
+	userprofile "github.com/Brrocat/car-sharing-protos/proto/userprofile"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FindProfileByPhoneRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Phone         string                 `protobuf:"bytes,1,opt,name=phone,proto3" json:"phone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindProfileByPhoneRequest) Reset() {
+	*x = FindProfileByPhoneRequest{}
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindProfileByPhoneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindProfileByPhoneRequest) ProtoMessage() {}
+
+func (x *FindProfileByPhoneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindProfileByPhoneRequest.ProtoReflect.Descriptor instead.
+func (*FindProfileByPhoneRequest) Descriptor() ([]byte, []int) {
+	return file_profilelookup_profile_lookup_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FindProfileByPhoneRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+type FindProfileByLicenseRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DrivingLicense string                 `protobuf:"bytes,1,opt,name=driving_license,json=drivingLicense,proto3" json:"driving_license,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *FindProfileByLicenseRequest) Reset() {
+	*x = FindProfileByLicenseRequest{}
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindProfileByLicenseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindProfileByLicenseRequest) ProtoMessage() {}
+
+func (x *FindProfileByLicenseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindProfileByLicenseRequest.ProtoReflect.Descriptor instead.
+func (*FindProfileByLicenseRequest) Descriptor() ([]byte, []int) {
+	return file_profilelookup_profile_lookup_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FindProfileByLicenseRequest) GetDrivingLicense() string {
+	if x != nil {
+		return x.DrivingLicense
+	}
+	return ""
+}
+
+type FindProfileResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Profile       *userprofile.UserProfile `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindProfileResponse) Reset() {
+	*x = FindProfileResponse{}
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindProfileResponse) ProtoMessage() {}
+
+func (x *FindProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profilelookup_profile_lookup_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindProfileResponse.ProtoReflect.Descriptor instead.
+func (*FindProfileResponse) Descriptor() ([]byte, []int) {
+	return file_profilelookup_profile_lookup_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FindProfileResponse) GetProfile() *userprofile.UserProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+var File_profilelookup_profile_lookup_proto protoreflect.FileDescriptor
+
+const file_profilelookup_profile_lookup_proto_rawDesc = "" +
+	"\n" +
+	"\"profilelookup/profile_lookup.proto\x12\rprofilelookup\x1a\x1euserprofile/user_profile.proto\"1\n" +
+	"\x19FindProfileByPhoneRequest\x12\x14\n" +
+	"\x05phone\x18\x01 \x01(\tR\x05phone\"F\n" +
+	"\x1bFindProfileByLicenseRequest\x12'\n" +
+	"\x0fdriving_license\x18\x01 \x01(\tR\x0edrivingLicense\"I\n" +
+	"\x13FindProfileResponse\x122\n" +
+	"\aprofile\x18\x01 \x01(\v2\x18.userprofile.UserProfileR\aprofile2\xe2\x01\n" +
+	"\x14ProfileLookupService\x12b\n" +
+	"\x12FindProfileByPhone\x12(.profilelookup.FindProfileByPhoneRequest\x1a\".profilelookup.FindProfileResponse\x12f\n" +
+	"\x14FindProfileByLicense\x12*.profilelookup.FindProfileByLicenseRequest\x1a\".profilelookup.FindProfileResponseB=Z;github.com/Brrocat/user-profile-service/proto/profilelookupb\x06proto3"
+
+var (
+	file_profilelookup_profile_lookup_proto_rawDescOnce sync.Once
+	file_profilelookup_profile_lookup_proto_rawDescData []byte
+)
+
+func file_profilelookup_profile_lookup_proto_rawDescGZIP() []byte {
+	file_profilelookup_profile_lookup_proto_rawDescOnce.Do(func() {
+		file_profilelookup_profile_lookup_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_profilelookup_profile_lookup_proto_rawDesc), len(file_profilelookup_profile_lookup_proto_rawDesc)))
+	})
+	return file_profilelookup_profile_lookup_proto_rawDescData
+}
+
+var file_profilelookup_profile_lookup_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_profilelookup_profile_lookup_proto_goTypes = []any{
+	(*FindProfileByPhoneRequest)(nil),   // 0: profilelookup.FindProfileByPhoneRequest
+	(*FindProfileByLicenseRequest)(nil), // 1: profilelookup.FindProfileByLicenseRequest
+	(*FindProfileResponse)(nil),         // 2: profilelookup.FindProfileResponse
+	(*userprofile.UserProfile)(nil),     // 3: userprofile.UserProfile
+}
+var file_profilelookup_profile_lookup_proto_depIdxs = []int32{
+	3, // 0: profilelookup.FindProfileResponse.profile:type_name -> userprofile.UserProfile
+	0, // 1: profilelookup.ProfileLookupService.FindProfileByPhone:input_type -> profilelookup.FindProfileByPhoneRequest
+	1, // 2: profilelookup.ProfileLookupService.FindProfileByLicense:input_type -> profilelookup.FindProfileByLicenseRequest
+	2, // 3: profilelookup.ProfileLookupService.FindProfileByPhone:output_type -> profilelookup.FindProfileResponse
+	2, // 4: profilelookup.ProfileLookupService.FindProfileByLicense:output_type -> profilelookup.FindProfileResponse
+	3, // [3:5] is the sub-list for method output_type
+	1, // [1:3] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_profilelookup_profile_lookup_proto_init() }
+func file_profilelookup_profile_lookup_proto_init() {
+	if File_profilelookup_profile_lookup_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_profilelookup_profile_lookup_proto_rawDesc), len(file_profilelookup_profile_lookup_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_profilelookup_profile_lookup_proto_goTypes,
+		DependencyIndexes: file_profilelookup_profile_lookup_proto_depIdxs,
+		MessageInfos:      file_profilelookup_profile_lookup_proto_msgTypes,
+	}.Build()
+	File_profilelookup_profile_lookup_proto = out.File
+	file_profilelookup_profile_lookup_proto_goTypes = nil
+	file_profilelookup_profile_lookup_proto_depIdxs = nil
+}
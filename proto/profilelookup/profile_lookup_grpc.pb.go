@@ -0,0 +1,165 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             (unknown)
+// source: profilelookup/profile_lookup.proto
+
+package profilelookup
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ProfileLookupService_FindProfileByPhone_FullMethodName   = "/profilelookup.ProfileLookupService/FindProfileByPhone"
+	ProfileLookupService_FindProfileByLicense_FullMethodName = "/profilelookup.ProfileLookupService/FindProfileByLicense"
+)
+
+// ProfileLookupServiceClient is the client API for ProfileLookupService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ProfileLookupService finds profiles by personal data through the blind
+// indexes. Callers limited to their own profile only ever find that profile.
+type ProfileLookupServiceClient interface {
+	FindProfileByPhone(ctx context.Context, in *FindProfileByPhoneRequest, opts ...grpc.CallOption) (*FindProfileResponse, error)
+	FindProfileByLicense(ctx context.Context, in *FindProfileByLicenseRequest, opts ...grpc.CallOption) (*FindProfileResponse, error)
+}
+
+type profileLookupServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProfileLookupServiceClient(cc grpc.ClientConnInterface) ProfileLookupServiceClient {
+	return &profileLookupServiceClient{cc}
+}
+
+func (c *profileLookupServiceClient) FindProfileByPhone(ctx context.Context, in *FindProfileByPhoneRequest, opts ...grpc.CallOption) (*FindProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindProfileResponse)
+	err := c.cc.Invoke(ctx, ProfileLookupService_FindProfileByPhone_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *profileLookupServiceClient) FindProfileByLicense(ctx context.Context, in *FindProfileByLicenseRequest, opts ...grpc.CallOption) (*FindProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindProfileResponse)
+	err := c.cc.Invoke(ctx, ProfileLookupService_FindProfileByLicense_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProfileLookupServiceServer is the server API for ProfileLookupService service.
+// All implementations must embed UnimplementedProfileLookupServiceServer
+// for forward compatibility.
+//
+// ProfileLookupService finds profiles by personal data through the blind
+// indexes. Callers limited to their own profile only ever find that profile.
+type ProfileLookupServiceServer interface {
+	FindProfileByPhone(context.Context, *FindProfileByPhoneRequest) (*FindProfileResponse, error)
+	FindProfileByLicense(context.Context, *FindProfileByLicenseRequest) (*FindProfileResponse, error)
+	mustEmbedUnimplementedProfileLookupServiceServer()
+}
+
+// UnimplementedProfileLookupServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedProfileLookupServiceServer struct{}
+
+func (UnimplementedProfileLookupServiceServer) FindProfileByPhone(context.Context, *FindProfileByPhoneRequest) (*FindProfileResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FindProfileByPhone not implemented")
+}
+func (UnimplementedProfileLookupServiceServer) FindProfileByLicense(context.Context, *FindProfileByLicenseRequest) (*FindProfileResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method FindProfileByLicense not implemented")
+}
+func (UnimplementedProfileLookupServiceServer) mustEmbedUnimplementedProfileLookupServiceServer() {}
+func (UnimplementedProfileLookupServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeProfileLookupServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProfileLookupServiceServer will
+// result in compilation errors.
+type UnsafeProfileLookupServiceServer interface {
+	mustEmbedUnimplementedProfileLookupServiceServer()
+}
+
+func RegisterProfileLookupServiceServer(s grpc.ServiceRegistrar, srv ProfileLookupServiceServer) {
+	// If the following call panics, it indicates UnimplementedProfileLookupServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ProfileLookupService_ServiceDesc, srv)
+}
+
+func _ProfileLookupService_FindProfileByPhone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindProfileByPhoneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileLookupServiceServer).FindProfileByPhone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileLookupService_FindProfileByPhone_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileLookupServiceServer).FindProfileByPhone(ctx, req.(*FindProfileByPhoneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProfileLookupService_FindProfileByLicense_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindProfileByLicenseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileLookupServiceServer).FindProfileByLicense(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileLookupService_FindProfileByLicense_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileLookupServiceServer).FindProfileByLicense(ctx, req.(*FindProfileByLicenseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ProfileLookupService_ServiceDesc is the grpc.ServiceDesc for ProfileLookupService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProfileLookupService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "profilelookup.ProfileLookupService",
+	HandlerType: (*ProfileLookupServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FindProfileByPhone",
+			Handler:    _ProfileLookupService_FindProfileByPhone_Handler,
+		},
+		{
+			MethodName: "FindProfileByLicense",
+			Handler:    _ProfileLookupService_FindProfileByLicense_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "profilelookup/profile_lookup.proto",
+}